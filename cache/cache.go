@@ -0,0 +1,251 @@
+// Package cache manages repositories of dotfiles downloaded locally
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NickHackman/dots/config"
+	"golang.org/x/tools/go/vcs"
+)
+
+// Cache is a wrapper around the path to the cache directory.
+//
+// The default case the Dir will be `XDG_CACHE_HOME/dots` or `~/.cache/dots`
+type Cache struct {
+	Dir         string
+	LockTimeout time.Duration              // How long to wait for a lock held by another process, `DefaultLockTimeout` if it isn't set
+	OnWait      func(lock string, pid int) // Called when a lock is held by another process before waiting for it, pid is 0 if it isn't known
+
+	Offline        bool          // Never access the network, fetching a repository fails immediately with an `OfflineError`
+	TTL            time.Duration // How long after being fetched a repository is fresh, see `Expired`, 0 for forever
+	NetworkTimeout time.Duration // How long fetching a repository can take, `DefaultNetworkTimeout` if it isn't set
+	Shallow        bool          // Clone only the latest commit of each branch, older commits are fetched when they're checked out
+}
+
+// DefaultCache creates a Cache instance using the default value of
+// `XDG_CACHE_HOME/dots`
+func DefaultCache() (*Cache, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cache directory: %w", err)
+	}
+	dir := filepath.Join(cacheDir, "dots")
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to mkdir %s: %w", dir, err)
+	}
+	// Older versions created the cache without any permissions
+	if stat, err := os.Stat(dir); err == nil && stat.Mode().Perm() == 0 {
+		if err = os.Chmod(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to chmod %s: %w", dir, err)
+		}
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+// UnsupportedVCSError is an error dictating that a repository uses a version control system other than git
+type UnsupportedVCSError struct {
+	Repo string // Repository as it was referenced
+	VCS  string // Name of the version control system it uses, such as `Mercurial`
+}
+
+// Error returns a String stating which version control system the repository uses
+func (uve *UnsupportedVCSError) Error() string {
+	return fmt.Sprintf("repository `%s` is a %s repository, only git repositories are supported", uve.Repo, uve.VCS)
+}
+
+// Directory inside of Cache.Dir that repositories are fetched into before they're moved into place
+const tmpDir = ".tmp"
+
+// Path the path a repository is at in the cache, whether or not it's been fetched
+func (cache *Cache) Path(repo *RepoRef) string {
+	return filepath.Join(cache.Dir, repo.CachePath())
+}
+
+// Get fetches a repository into the cache at `Path`, returning its path. If the repository is already
+// a hit it isn't fetched again, but its version is checked out if it has one, see `Checkout`.
+//
+// The repository is cloned into a temporary directory inside of the cache first and only moved into
+// place once it has a valid dots config, so a failed fetch never leaves a partial repository that's a hit.
+// Other processes getting, upgrading or removing the same repository are waited for.
+//
+// Repositories are cloned with git, a local repository that uses Mercurial, Subversion or Bazaar is an
+// `UnsupportedVCSError`.
+func (cache *Cache) Get(repo *RepoRef) (string, error) {
+	return cache.get(repo, nil)
+}
+
+// Gets repo, see `Get`, as a sparse checkout of only dotfiles if there are any
+func (cache *Cache) get(repo *RepoRef, dotfiles []string) (path string, err error) {
+	dest := cache.Path(repo)
+	lock, err := cache.lockRepo(dest)
+	if err != nil {
+		return "", err
+	}
+	defer releaseLock(lock, &err)
+
+	if cache.IsHit(repo.CachePath()) {
+		if len(dotfiles) == 0 && repo.Version == "" {
+			return dest, nil
+		}
+		// Widening without any dotfiles would check out every file
+		if len(dotfiles) != 0 {
+			if err = cache.widen(dest, dotfiles); err != nil {
+				return "", err
+			}
+		}
+		if repo.Version != "" {
+			if err = cache.checkoutRev(dest, repo.Version); err != nil {
+				return "", err
+			}
+		}
+		return dest, cache.index(dest)
+	}
+	if cache.Offline {
+		return "", &OfflineError{Repo: repo.String(), Action: "download"}
+	}
+	if cmd := repoVCS(repo); cmd.Cmd != "git" {
+		return "", &UnsupportedVCSError{Repo: repo.String(), VCS: cmd.Name}
+	}
+
+	tmpParent := filepath.Join(cache.Dir, tmpDir)
+	if err := os.MkdirAll(tmpParent, 0700); err != nil {
+		return "", fmt.Errorf("failed to mkdir %s: %w", tmpParent, err)
+	}
+	tmp, err := ioutil.TempDir(tmpParent, "get-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory in %s: %w", tmpParent, err)
+	}
+	defer os.RemoveAll(tmp)
+
+	clone := filepath.Join(tmp, "repo")
+	if err = cache.cloneGit(repo, clone, dotfiles); err != nil {
+		return "", err
+	}
+
+	if err = validateRepo(clone, repo.String()); err != nil {
+		return "", err
+	}
+
+	// Anything already at dest isn't a hit, so it's left over and replaced
+	if err = os.RemoveAll(dest); err != nil {
+		return "", fmt.Errorf("failed to remove `%s`: %w", dest, err)
+	}
+	if err = os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return "", fmt.Errorf("failed to mkdir %s: %w", filepath.Dir(dest), err)
+	}
+	if err = os.Rename(clone, dest); err != nil {
+		return "", fmt.Errorf("failed to move `%s` into the cache: %w", repo, err)
+	}
+	return dest, cache.index(dest)
+}
+
+// The version control system repo uses, found by `.git`, `.hg`, `.svn` or `.bzr` for a local repository,
+// any other repository is expected to use git
+func repoVCS(repo *RepoRef) *vcs.Cmd {
+	if repo.IsLocal() {
+		if cmd, _, err := vcs.FromDir(repo.Path, filepath.Dir(repo.Path)); err == nil {
+			return cmd
+		}
+	}
+	return vcs.ByCmd("git")
+}
+
+// Clones repo into clone with git, as a sparse checkout of only dotfiles if there are any
+func (cache *Cache) cloneGit(repo *RepoRef, clone string, dotfiles []string) error {
+	args := []string{"clone", "--quiet"}
+	if cache.Shallow {
+		// Every branch is fetched, so any of them can be checked out without fetching again
+		args = append(args, "--depth", "1", "--no-single-branch")
+	}
+	if len(dotfiles) != 0 {
+		args = append(args, "--no-checkout")
+	} else {
+		args = append(args, "--recurse-submodules")
+	}
+	if err := cache.gitNetwork("", repo.String(), append(args, "--", repo.URL, clone)...); err != nil {
+		return fmt.Errorf("failed to clone `%s`: %w", repo, err)
+	}
+	if len(dotfiles) != 0 {
+		// Only the root is checked out until the version is, since the dotfiles are looked up in its dots config
+		if err := git(clone, "config", "core.sparseCheckout", "true"); err != nil {
+			return err
+		}
+		if err := readSparse(clone, sparseBase); err != nil {
+			return fmt.Errorf("failed to check out `%s`: %w", repo, err)
+		}
+	}
+	if repo.Version != "" {
+		if err := cache.fetchRev(clone, repo.String(), repo.Version); err != nil {
+			return err
+		}
+		if err := checkout(clone, repo.Version); err != nil {
+			return err
+		}
+	}
+	if len(dotfiles) != 0 {
+		if err := setSparse(clone, dotfiles); err != nil {
+			return fmt.Errorf("failed to check out `%s`: %w", repo, err)
+		}
+	}
+	return nil
+}
+
+// IsHit checks to see if the cache already has a repository downloaded locally
+//
+// path is expected to be of the form `$domain/$username/$repoName`
+// for example: `github.com/NickHackman/dotfiles`
+//
+// The dots config recorded in the index is checked first, see `ReadIndex`, repositories that aren't
+// indexed are searched for a dots config.
+func (cache *Cache) IsHit(path string) bool {
+	repoPath := filepath.Join(cache.Dir, path)
+	if index, err := cache.ReadIndex(); err == nil {
+		if entry := index.Find(filepath.ToSlash(path)); entry != nil && entry.Config != "" {
+			_, err := os.Stat(filepath.Join(repoPath, filepath.FromSlash(entry.Config)))
+			return err == nil
+		}
+	}
+
+	file, err := os.Stat(repoPath)
+	if os.IsNotExist(err) || err != nil {
+		return false
+	}
+	if !file.IsDir() {
+		return false
+	}
+
+	dotsPath, err := config.FindConfigIn(repoPath)
+	if err != nil {
+		return false
+	}
+
+	if _, err = os.Stat(dotsPath); os.IsNotExist(err) || err != nil {
+		return false
+	}
+	return true
+}
+
+// Config gets the dots configuration file at a given repository path,
+// repository path is expected to be of the form `$domain/$username/$repoName`.
+//
+// Config cannot on its own determine if the cache is present, prior to calling `Config`
+// one should call `IsHit` to verify the existence of the cache.
+//
+// Deprecated: Config searches upwards from Cache.Dir rather than inside of a repository, use
+// `LoadConfig` or `LoadConfigAt` instead.
+func (cache *Cache) Config() (*config.DotsConfig, error) {
+	dotsConfig, err := config.Parse(cache.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return dotsConfig, nil
+}
+
+// Clean completely removes all sub directories of `Cache.Dir`
+func (cache *Cache) Clean() error {
+	return os.RemoveAll(cache.Dir)
+}
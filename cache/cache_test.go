@@ -0,0 +1,160 @@
+package cache_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultCache(t *testing.T) {
+	_, err := cache.DefaultCache()
+	assert.NoError(t, err)
+}
+
+func TestDefaultCachePermissions(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the cache directory is only set by XDG_CACHE_HOME on Linux")
+	}
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup cache_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	assert.NoError(t, os.Setenv("XDG_CACHE_HOME", dir))
+
+	dotsCache, err := cache.DefaultCache()
+	assert.NoError(t, err)
+	stat, err := os.Stat(dotsCache.Dir)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), stat.Mode().Perm())
+
+	// Created without any permissions by older versions
+	assert.NoError(t, os.Chmod(dotsCache.Dir, 0))
+	dotsCache, err = cache.DefaultCache()
+	assert.NoError(t, err)
+	stat, err = os.Stat(dotsCache.Dir)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), stat.Mode().Perm())
+}
+
+func TestIsHitMiss(t *testing.T) {
+	dotsCache, err := cache.DefaultCache()
+	assert.NoError(t, err)
+	hit := dotsCache.IsHit("github.com/this-user-doesn't-exist/not-a-repository-that-exists")
+	assert.False(t, hit)
+}
+
+func TestCacheConfig(t *testing.T) {
+	_, filename, _, ok := runtime.Caller(0)
+	assert.Truef(t, ok, "failed to get filename of test file cache_test.go")
+	parent := filepath.Dir(filepath.Dir(filename))
+	testDataSlash := fmt.Sprintf("%s/testdata", parent)
+	dotsCache := &cache.Cache{Dir: filepath.FromSlash(testDataSlash)}
+	conf, err := dotsCache.Config()
+	assert.NoError(t, err)
+	assert.Equal(t, conf.License, "GPL-3.0-only")
+	assert.Equal(t, conf.Name, "YourName/dotfiles")
+}
+
+func TestCleanDoesntExist(t *testing.T) {
+	invalidFile := "this-isn't-a-present-file-please-don't-let-this-be-a-present-file.abcdefgh"
+	dotsCache := &cache.Cache{Dir: invalidFile}
+	err := dotsCache.Clean()
+	assert.NoError(t, err)
+}
+
+// Runs git in dir with an identity set, so commits work regardless of the global git config
+func runGit(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-c", "user.name=dots", "-c", "user.email=dots@example.com", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	assert.NoErrorf(t, err, "failed to run `git %v`: %s", args, output)
+}
+
+// Creates a git repository in dir containing files, by their path relative to dir, in a single commit
+func createRepo(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	runGit(t, dir, "init", "--quiet")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "--quiet", "-m", "Add dotfiles")
+}
+
+const validDotsConfig = `version: 1
+name: NickHackman/dotfiles
+license: MIT
+dotfiles:
+  - name: zsh
+    description: Z shell
+`
+
+func TestGet(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup cache_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	valid, invalid, empty := filepath.Join(dir, "valid"), filepath.Join(dir, "invalid"), filepath.Join(dir, "empty")
+	createRepo(t, valid, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+	createRepo(t, invalid, map[string]string{".dots.yml": "version: 1\nname: NickHackman/dotfiles\nlicense: MIT\ndotfiles:\n  - name: zsh\n"})
+	createRepo(t, empty, map[string]string{"README.md": "# dotfiles\n"})
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	for _, path := range []string{invalid, empty, filepath.Join(dir, "missing")} {
+		repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(path))
+		assert.NoError(t, err)
+		_, err = dotsCache.Get(repo)
+		assert.Errorf(t, err, "getting `%s` should fail", path)
+		assert.False(t, dotsCache.IsHit(repo.CachePath()))
+		_, err = os.Stat(dotsCache.Path(repo))
+		assert.Truef(t, os.IsNotExist(err), "failed get left `%s` behind", dotsCache.Path(repo))
+	}
+	tmp, err := ioutil.ReadDir(filepath.Join(dotsCache.Dir, ".tmp"))
+	assert.NoError(t, err)
+	assert.Empty(t, tmp)
+
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(valid))
+	assert.NoError(t, err)
+	path, err := dotsCache.Get(repo)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dotsCache.Dir, "file", valid), path)
+	assert.True(t, dotsCache.IsHit(repo.CachePath()))
+	assert.FileExists(t, filepath.Join(path, "zsh", ".zshrc"))
+
+	path, err = dotsCache.Get(repo)
+	assert.NoError(t, err)
+	assert.Equal(t, dotsCache.Path(repo), path)
+}
+
+func TestGetOtherVCS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup cache_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	// Only git repositories can be downloaded, a local repository that uses another VCS is rejected
+	source := filepath.Join(dir, "source")
+	assert.NoError(t, os.MkdirAll(filepath.Join(source, ".hg"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(source, ".dots.yml"), []byte(validDotsConfig), 0644))
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	_, err = dotsCache.Get(repo)
+	assert.Equal(t, &cache.UnsupportedVCSError{Repo: repo.String(), VCS: "Mercurial"}, err)
+	_, err = dotsCache.GetSparse(repo, "zsh")
+	assert.Equal(t, &cache.UnsupportedVCSError{Repo: repo.String(), VCS: "Mercurial"}, err)
+	assert.False(t, dotsCache.IsHit(repo.CachePath()))
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/NickHackman/dots/config"
+	"golang.org/x/tools/go/vcs"
+)
+
+// RepoConfig the dots config of a repository in the cache along with which repository it belongs to
+type RepoConfig struct {
+	Ref        string             // Canonical reference without a version, `github.com/YourName/dotfiles`
+	URL        string             // URL the repository was cloned from, empty if it isn't known
+	Name       string             // Path relative to Cache.Dir, using `/` as the separator, such as `github.com/YourName/dotfiles`
+	Path       string             // Path to the root of the repository
+	ConfigPath string             // Path to the dots config in the root of the repository
+	Config     *config.DotsConfig // Parsed dots config
+}
+
+// LoadConfig loads the dots config of repo, which MUST be in the cache
+//
+// Only the root of the repository is searched for a dots config, never any directory above it.
+func (cache *Cache) LoadConfig(repo *RepoRef) (*RepoConfig, error) {
+	path := cache.Path(repo)
+	if stat, err := os.Stat(path); err != nil || !stat.IsDir() {
+		return nil, &NotCachedError{Repo: repo.String(), Path: path}
+	}
+	return cache.LoadConfigAt(path)
+}
+
+// LoadConfigAt loads the dots config of the repository in the cache at path, either its root or a
+// directory inside of it, path is expected to be relative to the working directory in the same way as Cache.Dir
+//
+// Only the root of the repository is searched for a dots config, never any directory above it.
+func (cache *Cache) LoadConfigAt(path string) (*RepoConfig, error) {
+	root, err := cache.repoRoot(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	name, err := filepath.Rel(cache.Dir, root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find `%s` relative to the cache `%s`: %w", root, cache.Dir, err)
+	}
+
+	repoConf := &RepoConfig{Name: filepath.ToSlash(name), Path: root}
+	if _, repoConf.URL, repoConf.Ref, err = cache.identify(root); err != nil {
+		return nil, err
+	}
+	if repoConf.ConfigPath, repoConf.Config, err = loadConfig(root); err != nil {
+		return nil, fmt.Errorf("failed to load the dots config of `%s`: %w", repoConf.Ref, err)
+	}
+	return repoConf, nil
+}
+
+// Finds the root of the repository in the cache containing path, the closest directory to path that's
+// inside of Cache.Dir and contains `.git`, `.hg`, `.svn` or `.bzr`
+func (cache *Cache) repoRoot(path string) (string, error) {
+	for current := path; current != cache.Dir && isWithin(cache.Dir, current); current = filepath.Dir(current) {
+		if current == filepath.Join(cache.Dir, tmpDir) {
+			break
+		}
+		for _, marker := range vcsMarkers {
+			if _, err := os.Stat(filepath.Join(current, marker)); err == nil {
+				return current, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("`%s` isn't inside of a repository in the cache `%s`", path, cache.Dir)
+}
+
+// Loads the dots config in root, the root of a repository
+func loadConfig(root string) (string, *config.DotsConfig, error) {
+	configPath, err := config.FindConfigIn(root)
+	if err != nil {
+		return "", nil, err
+	}
+	dotsConf, err := config.ParseFile(configPath)
+	return configPath, dotsConf, err
+}
+
+// Identifies the repository at path, its version control system, the URL it was cloned from and its
+// canonical reference, which is its path relative to Cache.Dir when the URL isn't known
+func (cache *Cache) identify(path string) (vcsName, url, ref string, err error) {
+	cmd, _, err := vcs.FromDir(path, filepath.Dir(path))
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to find repository `%s`: %w", path, err)
+	}
+	if cmd.Cmd == "git" {
+		if output, err := exec.Command("git", "-C", path, "config", "--get", "remote.origin.url").Output(); err == nil {
+			url = strings.TrimSpace(string(output))
+		}
+	}
+	if repo, err := ParseRepoRef(url); url != "" && err == nil {
+		repo.Version = ""
+		return cmd.Cmd, url, repo.String(), nil
+	}
+
+	name, err := filepath.Rel(cache.Dir, path)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to find `%s` relative to the cache `%s`: %w", path, cache.Dir, err)
+	}
+	return cmd.Cmd, url, filepath.ToSlash(name), nil
+}
@@ -0,0 +1,63 @@
+package cache_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup config_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	source := filepath.Join(dir, "source")
+	createRepo(t, source, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+	_, err = dotsCache.LoadConfig(repo)
+	assert.IsType(t, &cache.NotCachedError{}, err)
+
+	path, err := dotsCache.Get(repo)
+	assert.NoError(t, err)
+	for _, loaded := range []func() (*cache.RepoConfig, error){
+		func() (*cache.RepoConfig, error) { return dotsCache.LoadConfig(repo) },
+		func() (*cache.RepoConfig, error) { return dotsCache.LoadConfigAt(path) },
+		func() (*cache.RepoConfig, error) { return dotsCache.LoadConfigAt(filepath.Join(path, "zsh")) },
+	} {
+		repoConf, err := loaded()
+		if assert.NoError(t, err) {
+			assert.Equal(t, repo.String(), repoConf.Ref)
+			assert.Equal(t, repo.URL, repoConf.URL)
+			assert.Equal(t, filepath.ToSlash(repo.CachePath()), repoConf.Name)
+			assert.Equal(t, path, repoConf.Path)
+			assert.Equal(t, filepath.Join(path, ".dots.yml"), repoConf.ConfigPath)
+			assert.Equal(t, "NickHackman/dotfiles", repoConf.Config.Name)
+		}
+	}
+
+	// Dots configs above the repository are never used, even without a repository root marker
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dotsCache.Dir, ".dots.yml"), []byte(validDotsConfig), 0644))
+	bare := filepath.Join(dotsCache.Dir, "example.com", "NickHackman", "bare")
+	assert.NoError(t, os.MkdirAll(filepath.Join(bare, ".hg"), 0755))
+	_, err = dotsCache.LoadConfigAt(bare)
+	var rootErr *config.RepositoryRootError
+	assert.True(t, errors.As(err, &rootErr))
+
+	_, err = dotsCache.LoadConfigAt(filepath.Join(dotsCache.Dir, "example.com"))
+	assert.Error(t, err)
+	_, err = dotsCache.LoadConfigAt(source)
+	assert.Error(t, err)
+}
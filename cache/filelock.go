@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLockTimeout how long to wait for a lock held by another process when Cache.LockTimeout isn't set
+const DefaultLockTimeout = 5 * time.Minute
+
+// Directory inside of Cache.Dir that lock files are created in
+const locksDir = ".locks"
+
+// Name of the lock file that guards writes to state shared by every repository, the index and lockfile
+const stateLock = "state.lock"
+
+// How often a lock held by another process is checked
+const lockPollInterval = 100 * time.Millisecond
+
+// LockTimeoutError is an error dictating that a lock held by another process wasn't released in time
+type LockTimeoutError struct {
+	Path    string        // Path to the lock file
+	PID     int           // Process holding the lock, 0 if it isn't known
+	Timeout time.Duration // How long was waited
+}
+
+// Error returns a String stating which lock timed out and the process holding it
+func (lte *LockTimeoutError) Error() string {
+	holder := "another process"
+	if lte.PID != 0 {
+		holder = fmt.Sprintf("pid %d", lte.PID)
+	}
+	return fmt.Sprintf("timed out after %s waiting for lock `%s` held by %s", lte.Timeout, lte.Path, holder)
+}
+
+// FileLock an advisory lock held by this process on a lock file, flock on unix and LockFileEx on windows
+//
+// The operating system releases the lock when the process holding it exits, so a process that exits
+// without releasing its locks never leaves them held. Lock files are never removed, while the lock is held
+// the file contains the pid of the process holding it, only so it can be reported to processes waiting.
+type FileLock struct {
+	file *os.File
+}
+
+// Release releases the lock, so other processes can acquire it
+func (lock *FileLock) Release() error {
+	// The pid is cleared while the lock is still held, so it's never of a process that released it
+	err := lock.file.Truncate(0)
+	if unlockErr := unlockFile(lock.file); err == nil {
+		err = unlockErr
+	}
+	if closeErr := lock.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release lock `%s`: %w", lock.file.Name(), err)
+	}
+	return nil
+}
+
+// Acquires the lock for the repository at path, guarding fetching, checking out, upgrading and removing it
+func (cache *Cache) lockRepo(path string) (*FileLock, error) {
+	name, err := filepath.Rel(cache.Dir, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find `%s` relative to the cache `%s`: %w", path, cache.Dir, err)
+	}
+	return cache.acquire(url.PathEscape(filepath.ToSlash(name)) + ".lock")
+}
+
+// LockState acquires the lock guarding writes to state shared by every repository, the index and lockfile,
+// it MUST NOT be held while acquiring the lock of a repository
+func (cache *Cache) LockState() (*FileLock, error) {
+	return cache.acquire(stateLock)
+}
+
+// Acquires the lock on the file name inside of `locksDir`, waiting up to Cache.LockTimeout for another
+// process to release it and calling Cache.OnWait once if it has to wait
+func (cache *Cache) acquire(name string) (*FileLock, error) {
+	dir := filepath.Join(cache.Dir, locksDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to mkdir %s: %w", dir, err)
+	}
+	timeout := cache.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+
+	path := filepath.Join(dir, name)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock `%s`: %w", path, err)
+	}
+	deadline := time.Now().Add(timeout)
+	waited := false
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to acquire lock `%s`: %w", path, err)
+		} else if locked {
+			break
+		}
+
+		pid := lockHolder(path)
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, &LockTimeoutError{Path: path, PID: pid, Timeout: timeout}
+		}
+		if !waited && cache.OnWait != nil {
+			cache.OnWait(path, pid)
+		}
+		waited = true
+		time.Sleep(lockPollInterval)
+	}
+
+	// A process that exited while holding the lock left its pid behind
+	lock := &FileLock{file: file}
+	if err = file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+	}
+	if err != nil {
+		lock.Release()
+		return nil, fmt.Errorf("failed to write lock `%s`: %w", path, err)
+	}
+	return lock, nil
+}
+
+// The pid of the process holding the lock at path, 0 if it isn't known such as while it's being written
+func lockHolder(path string) int {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
+
+// Releases lock, replacing err with the error releasing it if err is nil
+func releaseLock(lock *FileLock, err *error) {
+	if releaseErr := lock.Release(); *err == nil {
+		*err = releaseErr
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package cache
+
+import "os"
+
+// Advisory locks aren't supported, so locking always succeeds and dots commands run at once aren't
+// guarded from one another
+func tryLockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+// Advisory locks aren't supported, there's nothing to unlock
+func unlockFile(file *os.File) error {
+	return nil
+}
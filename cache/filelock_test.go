@@ -0,0 +1,138 @@
+package cache_test
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup filelock_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	var waits []int
+	dotsCache := &cache.Cache{
+		Dir:         dir,
+		LockTimeout: 300 * time.Millisecond,
+		OnWait:      func(lock string, pid int) { waits = append(waits, pid) },
+	}
+	lock, err := dotsCache.LockState()
+	assert.NoError(t, err)
+
+	_, err = dotsCache.LockState()
+	if assert.IsType(t, &cache.LockTimeoutError{}, err) {
+		assert.Equal(t, os.Getpid(), err.(*cache.LockTimeoutError).PID)
+	}
+	assert.Equal(t, []int{os.Getpid()}, waits, "waiting is reported once")
+
+	assert.NoError(t, lock.Release())
+	lock, err = dotsCache.LockState()
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+func TestLockStateExited(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup filelock_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	dotsCache := &cache.Cache{Dir: dir, LockTimeout: 300 * time.Millisecond}
+	path := filepath.Join(dir, ".locks", "state.lock")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+
+	// A lock file isn't a held lock, regardless of the pid in it
+	assert.NoError(t, ioutil.WriteFile(path, []byte("1\n"), 0600))
+	lock, err := dotsCache.LockState()
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+
+	// Held by another process that's killed without releasing it
+	holder := exec.Command(os.Args[0], "-test.run=^TestLockHelper$")
+	holder.Env = append(os.Environ(), "DOTS_TEST_LOCK_CACHE="+dir)
+	stdout, err := holder.StdoutPipe()
+	assert.NoError(t, err)
+	assert.NoError(t, holder.Start())
+	defer holder.Process.Kill()
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "locked\n", line)
+
+	_, err = dotsCache.LockState()
+	if assert.IsType(t, &cache.LockTimeoutError{}, err) {
+		assert.Equal(t, holder.Process.Pid, err.(*cache.LockTimeoutError).PID)
+	}
+
+	assert.NoError(t, holder.Process.Kill())
+	holder.Wait()
+	lock, err = dotsCache.LockState()
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+// TestLockHelper isn't a test, it's run by TestLockStateExited in another process to hold the state lock
+// of the cache in `$DOTS_TEST_LOCK_CACHE` until it's killed
+func TestLockHelper(t *testing.T) {
+	dir := os.Getenv("DOTS_TEST_LOCK_CACHE")
+	if dir == "" {
+		return
+	}
+	if _, err := (&cache.Cache{Dir: dir}).LockState(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("locked")
+	time.Sleep(time.Minute)
+	os.Exit(1)
+}
+
+func TestGetConcurrent(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup filelock_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source")
+	createRepo(t, source, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = dotsCache.Get(repo)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.True(t, dotsCache.IsHit(repo.CachePath()))
+
+	index, err := dotsCache.ReadIndex()
+	assert.NoError(t, err)
+	assert.Len(t, index.Repos, 1)
+
+	// Every lock is released, so they're acquired without waiting
+	dotsCache.LockTimeout = time.Millisecond
+	lock, err := dotsCache.LockState()
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+	assert.NoError(t, dotsCache.Remove(repo))
+}
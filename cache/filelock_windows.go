@@ -0,0 +1,48 @@
+//go:build windows
+// +build windows
+
+package cache
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// Locks on windows are mandatory, so the byte locked is far past the pid written to the lock file and
+// processes waiting for the lock can still read it
+const lockedOffsetHigh = 0x7fffffff
+
+// Locks file with LockFileEx without waiting, false if another process holds the lock
+func tryLockFile(file *os.File) (bool, error) {
+	overlapped := syscall.Overlapped{OffsetHigh: lockedOffsetHigh}
+	ok, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if ok != 0 {
+		return true, nil
+	}
+	if err == errorLockViolation || err == syscall.ERROR_IO_PENDING {
+		return false, nil
+	}
+	return false, err
+}
+
+// Unlocks file, which this process has locked
+func unlockFile(file *os.File) error {
+	overlapped := syscall.Overlapped{OffsetHigh: lockedOffsetHigh}
+	if ok, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped))); ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NickHackman/dots/config"
+)
+
+// Name of the index file inside of Cache.Dir
+const indexFile = "index.json"
+
+// Version of the index format, an index written by another version is rebuilt by `Reindex`
+const indexVersion = 2
+
+// Index information about every repository in the cache, kept up to date by `Get`, `Checkout`,
+// `Upgrade`, `UpgradeAll`, `Remove` and `Prune`, so the cache can be listed without reading every
+// repository
+type Index struct {
+	Version int          `json:"version"`      // Version of the index format
+	Repos   []IndexEntry `json:"repositories"` // Every indexed repository sorted by Name
+}
+
+// IndexEntry a repository in the Index
+type IndexEntry struct {
+	Name      string           `json:"name"`                   // Path relative to Cache.Dir, using `/` as the separator, such as `github.com/YourName/dotfiles`
+	Ref       string           `json:"ref"`                    // Canonical reference without a version, `github.com/YourName/dotfiles`
+	URL       string           `json:"url,omitempty"`          // URL the repository was cloned from, empty if it isn't known
+	VCS       string           `json:"vcs"`                    // Version control system of the repository, such as `git`
+	Commit    string           `json:"commit,omitempty"`       // Commit HEAD points to, empty if it isn't a git repository
+	Fetched   time.Time        `json:"fetched"`                // When the repository was last fetched, see `RepoInfo.Fetched`
+	Modified  time.Time        `json:"modified,omitempty"`     // When HEAD, the branch it points to or the git index last changed, see `Stale`
+	Indexed   time.Time        `json:"indexed"`                // When the repository was indexed
+	Config    string           `json:"config,omitempty"`       // Path to the dots config relative to the repository, empty if there isn't one
+	DotsName  string           `json:"dots_name,omitempty"`    // Name in the dots config
+	License   string           `json:"license,omitempty"`      // License in the dots config
+	Dotfiles  []IndexedDotfile `json:"dotfiles,omitempty"`     // Every dotfile in the dots config
+	ConfigErr string           `json:"config_error,omitempty"` // Error that occurred finding or parsing the dots config
+	Shallow   bool             `json:"shallow,omitempty"`      // Whether only the latest commits were fetched
+	Sparse    []string         `json:"sparse,omitempty"`       // Dotfiles checked out in a sparse repository, empty if every file is
+}
+
+// IndexedDotfile a summary of a dotfile in an IndexEntry
+type IndexedDotfile struct {
+	Name        string `json:"name"`                  // Name of the dotfile
+	Description string `json:"description,omitempty"` // Description of the dotfile
+	Source      string `json:"source"`                // Source relative to the repository, using `/` as the separator
+}
+
+// StaleRepo a repository whose IndexEntry doesn't match the repository, or that isn't indexed
+type StaleRepo struct {
+	Name   string // Path relative to Cache.Dir, using `/` as the separator
+	Reason string // Why it's stale, such as `the commit changed`
+}
+
+// Find the entry for the repository at name, relative to Cache.Dir, nil if it isn't indexed
+func (index *Index) Find(name string) *IndexEntry {
+	for i := range index.Repos {
+		if index.Repos[i].Name == name {
+			return &index.Repos[i]
+		}
+	}
+	return nil
+}
+
+// Sets the entry for its repository, replacing the repository if it's already indexed
+func (index *Index) set(entry IndexEntry) {
+	if existing := index.Find(entry.Name); existing != nil {
+		*existing = entry
+		return
+	}
+	index.Repos = append(index.Repos, entry)
+}
+
+// Removes the entry for the repository at name
+func (index *Index) remove(name string) {
+	for i := range index.Repos {
+		if index.Repos[i].Name == name {
+			index.Repos = append(index.Repos[:i], index.Repos[i+1:]...)
+			return
+		}
+	}
+}
+
+// IndexPath the path to the index file
+func (cache *Cache) IndexPath() string {
+	return filepath.Join(cache.Dir, indexFile)
+}
+
+// ReadIndex reads the index of the cache, if it doesn't exist or was written by another version of the
+// format an empty Index is returned, so every repository is stale
+func (cache *Cache) ReadIndex() (*Index, error) {
+	index := &Index{Version: indexVersion}
+	contents, err := ioutil.ReadFile(cache.IndexPath())
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the cache index `%s`: %w", cache.IndexPath(), err)
+	}
+
+	read := &Index{}
+	if err = json.Unmarshal(contents, read); err != nil {
+		return nil, fmt.Errorf("failed to parse the cache index `%s`, rebuild it with `dots cache reindex`: %w", cache.IndexPath(), err)
+	}
+	if read.Version != indexVersion {
+		return index, nil
+	}
+	return read, nil
+}
+
+// Writes index to `IndexPath`
+func (cache *Cache) writeIndex(index *Index) error {
+	index.Version = indexVersion
+	sort.Slice(index.Repos, func(i, j int) bool { return index.Repos[i].Name < index.Repos[j].Name })
+
+	var contents bytes.Buffer
+	encoder := json.NewEncoder(&contents)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(index); err != nil {
+		return fmt.Errorf("failed to encode the cache index: %w", err)
+	}
+	if err := os.MkdirAll(cache.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to mkdir %s: %w", cache.Dir, err)
+	}
+	if err := writeFileAtomic(cache.IndexPath(), contents.Bytes()); err != nil {
+		return fmt.Errorf("failed to write the cache index: %w", err)
+	}
+	return nil
+}
+
+// Reads the index, applies update to it and writes it back while holding the state lock, see `LockState`
+func (cache *Cache) updateIndex(update func(index *Index) error) (err error) {
+	lock, err := cache.LockState()
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock, &err)
+
+	index, err := cache.ReadIndex()
+	if err != nil {
+		return err
+	}
+	if err = update(index); err != nil {
+		return err
+	}
+	return cache.writeIndex(index)
+}
+
+// Indexes each of the repositories at paths, replacing their entries
+func (cache *Cache) index(paths ...string) error {
+	return cache.updateIndex(func(index *Index) error {
+		for _, path := range paths {
+			entry, err := cache.indexEntry(path)
+			if err != nil {
+				return err
+			}
+			index.set(*entry)
+		}
+		return nil
+	})
+}
+
+// Removes the repository at path from the index
+func (cache *Cache) unindex(path string) error {
+	name, err := filepath.Rel(cache.Dir, path)
+	if err != nil {
+		return fmt.Errorf("failed to find `%s` relative to the cache `%s`: %w", path, cache.Dir, err)
+	}
+	return cache.updateIndex(func(index *Index) error {
+		index.remove(filepath.ToSlash(name))
+		return nil
+	})
+}
+
+// Reindex rebuilds the index from every repository in the cache, see `Repos`
+func (cache *Cache) Reindex() (*Index, error) {
+	repos, err := cache.Repos()
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{Version: indexVersion}
+	err = cache.updateIndex(func(existing *Index) error {
+		for _, path := range repos {
+			entry, err := cache.indexEntry(path)
+			if err != nil {
+				return err
+			}
+			index.set(*entry)
+		}
+		*existing = *index
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Stale finds every repository in the cache whose entry in index is out of date, every repository that
+// isn't indexed, and every entry whose repository no longer exists, sorted by Name
+//
+// Only the modification times of the files git writes when a repository is fetched, committed to or
+// checked out and of its dots config are compared with index, no dots config is parsed.
+func (cache *Cache) Stale(index *Index) ([]StaleRepo, error) {
+	repos, err := cache.Repos()
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []StaleRepo
+	found := make(map[string]bool, len(repos))
+	for _, path := range repos {
+		name, err := filepath.Rel(cache.Dir, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find `%s` relative to the cache `%s`: %w", path, cache.Dir, err)
+		}
+		name = filepath.ToSlash(name)
+		found[name] = true
+
+		reason, err := staleReason(index.Find(name), path)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			stale = append(stale, StaleRepo{Name: name, Reason: reason})
+		}
+	}
+	for _, entry := range index.Repos {
+		if !found[entry.Name] {
+			stale = append(stale, StaleRepo{Name: entry.Name, Reason: "it was removed from the cache"})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Name < stale[j].Name })
+	return stale, nil
+}
+
+// Why entry is out of date for the repository at path, empty if it isn't
+func staleReason(entry *IndexEntry, path string) (string, error) {
+	if entry == nil {
+		return "it isn't indexed", nil
+	}
+	modified, err := gitModified(path)
+	if err != nil {
+		return "", err
+	}
+	if !entry.Modified.Equal(modified) {
+		return "its commit changed", nil
+	}
+	fetched, err := lastFetched(path)
+	if err != nil {
+		return "", err
+	}
+	if !entry.Fetched.Equal(fetched) {
+		return "it was fetched", nil
+	}
+
+	configPath, err := config.FindConfigIn(path)
+	if err != nil {
+		if entry.Config != "" {
+			return "its dots config was removed", nil
+		}
+		return "", nil
+	}
+	if rel, err := filepath.Rel(path, configPath); err != nil || filepath.ToSlash(rel) != entry.Config {
+		return "its dots config moved", nil
+	}
+	if stat, err := os.Stat(configPath); err == nil && stat.ModTime().After(entry.Indexed) {
+		return "its dots config changed", nil
+	}
+	return "", nil
+}
+
+// When the git repository at path last changed its commit, the latest modification time of HEAD, the
+// branch it points to, packed refs and the git index, zero if it isn't a git repository
+func gitModified(path string) (time.Time, error) {
+	gitDir := filepath.Join(path, ".git")
+	files := []string{"HEAD", "index", "packed-refs"}
+	if head, err := ioutil.ReadFile(filepath.Join(gitDir, "HEAD")); err == nil {
+		if ref := strings.TrimSpace(string(head)); strings.HasPrefix(ref, "ref: ") {
+			files = append(files, filepath.FromSlash(strings.TrimPrefix(ref, "ref: ")))
+		}
+	}
+
+	var modified time.Time
+	for _, file := range files {
+		stat, err := os.Stat(filepath.Join(gitDir, file))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat `%s`: %w", filepath.Join(gitDir, file), err)
+		}
+		if stat.ModTime().After(modified) {
+			modified = stat.ModTime()
+		}
+	}
+	return modified, nil
+}
+
+// Builds the entry for the repository at path
+func (cache *Cache) indexEntry(path string) (*IndexEntry, error) {
+	info, err := cache.Info(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &IndexEntry{
+		Name:    info.Name,
+		Commit:  info.Commit,
+		Fetched: info.Fetched,
+		Indexed: time.Now(),
+	}
+
+	if entry.VCS, entry.URL, entry.Ref, err = cache.identify(path); err != nil {
+		return nil, err
+	}
+	if entry.Modified, err = gitModified(path); err != nil {
+		return nil, err
+	}
+
+	if info.ConfigErr != nil {
+		entry.ConfigErr = info.ConfigErr.Error()
+	}
+	if entry.VCS == "git" {
+		entry.Shallow = shallow(path)
+		if entry.Sparse, err = SparseDotfiles(path); err != nil {
+			return nil, err
+		}
+	}
+	if configPath, err := config.FindConfigIn(path); err == nil {
+		if rel, err := filepath.Rel(path, configPath); err == nil {
+			entry.Config = filepath.ToSlash(rel)
+		}
+	}
+	if info.Config == nil {
+		return entry, nil
+	}
+
+	entry.DotsName, entry.License = info.Config.Name, info.Config.License
+	for _, dot := range info.Config.Dotfiles {
+		source, err := filepath.Rel(path, dot.Source)
+		if err != nil {
+			source = dot.Source
+		}
+		entry.Dotfiles = append(entry.Dotfiles, IndexedDotfile{Name: dot.Name, Description: dot.Description, Source: filepath.ToSlash(source)})
+	}
+	return entry, nil
+}
+
+// Writes contents to path by writing a temporary file beside it and renaming it into place, so path is
+// never partially written
+func writeFileAtomic(path string, contents []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return fmt.Errorf("failed to write `%s`: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write `%s`: %w", path, err)
+	}
+	return nil
+}
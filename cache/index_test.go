@@ -0,0 +1,108 @@
+package cache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup index_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	index, err := dotsCache.ReadIndex()
+	assert.NoError(t, err)
+	assert.Empty(t, index.Repos)
+
+	source := filepath.Join(dir, "source")
+	createRepo(t, source, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+	path, err := dotsCache.Get(repo)
+	assert.NoError(t, err)
+	commit, err := dotsCache.Commit(path)
+	assert.NoError(t, err)
+
+	index, err = dotsCache.ReadIndex()
+	assert.NoError(t, err)
+	name := filepath.ToSlash(repo.CachePath())
+	entry := index.Find(name)
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, repo.String(), entry.Ref)
+		assert.Equal(t, repo.URL, entry.URL)
+		assert.Equal(t, "git", entry.VCS)
+		assert.Equal(t, commit, entry.Commit)
+		assert.False(t, entry.Fetched.IsZero())
+		assert.False(t, entry.Modified.IsZero())
+		assert.Equal(t, ".dots.yml", entry.Config)
+		assert.Equal(t, "NickHackman/dotfiles", entry.DotsName)
+		assert.Equal(t, "MIT", entry.License)
+		assert.Equal(t, []cache.IndexedDotfile{{Name: "zsh", Description: "Z shell", Source: "zsh"}}, entry.Dotfiles)
+		assert.Empty(t, entry.ConfigErr)
+	}
+	stale, err := dotsCache.Stale(index)
+	assert.NoError(t, err)
+	assert.Empty(t, stale)
+
+	// Changes made outside of dots make the index stale
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(path, "zsh", ".zshrc"), []byte("export EDITOR=vim\n"), 0644))
+	runGit(t, path, "commit", "--quiet", "-am", "Use vim")
+	other := filepath.Join(dotsCache.Dir, "example.com", "NickHackman", "other")
+	createRepo(t, other, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+	index.Repos = append(index.Repos, cache.IndexEntry{Name: "example.com/NickHackman/removed"})
+
+	// Repositories that aren't indexed are still hits
+	assert.True(t, dotsCache.IsHit(filepath.Join("example.com", "NickHackman", "other")))
+
+	stale, err = dotsCache.Stale(index)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.StaleRepo{
+		{Name: "example.com/NickHackman/other", Reason: "it isn't indexed"},
+		{Name: "example.com/NickHackman/removed", Reason: "it was removed from the cache"},
+		{Name: name, Reason: "its commit changed"},
+	}, stale)
+
+	index, err = dotsCache.Reindex()
+	assert.NoError(t, err)
+	assert.Len(t, index.Repos, 2)
+	entry = index.Find("example.com/NickHackman/other")
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, "example.com/NickHackman/other", entry.Ref)
+		assert.Empty(t, entry.URL)
+	}
+	stale, err = dotsCache.Stale(index)
+	assert.NoError(t, err)
+	assert.Empty(t, stale)
+
+	// Checking out another branch changes HEAD
+	runGit(t, path, "checkout", "--quiet", "-b", "other")
+	stale, err = dotsCache.Stale(index)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.StaleRepo{{Name: name, Reason: "its commit changed"}}, stale)
+
+	// Hits are found from the dots config in the index
+	assert.True(t, dotsCache.IsHit(repo.CachePath()))
+	assert.NoError(t, os.Remove(filepath.Join(path, ".dots.yml")))
+	assert.False(t, dotsCache.IsHit(repo.CachePath()))
+
+	// An index written by another version of the format is empty
+	assert.NoError(t, ioutil.WriteFile(dotsCache.IndexPath(), []byte(`{"version": 1, "repositories": [{"name": "a/b/c"}]}`), 0600))
+	index, err = dotsCache.ReadIndex()
+	assert.NoError(t, err)
+	assert.Empty(t, index.Repos)
+
+	assert.NoError(t, ioutil.WriteFile(dotsCache.IndexPath(), []byte("{"), 0600))
+	_, err = dotsCache.ReadIndex()
+	assert.Error(t, err)
+}
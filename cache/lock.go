@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NickHackman/dots/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Written at the top of every lockfile
+const lockfileHeader = `# This file is generated by dots, it records the exact commit of every repository
+# downloaded by ` + "`dots get`" + `, to download the same commits on another machine run
+#
+# $ dots get --locked
+`
+
+// Lockfile the exact commit of every repository downloaded by `dots get`, so the same commits
+// can be downloaded on another machine with `dots get --locked`
+type Lockfile struct {
+	Repos []LockedRepo `yaml:"repositories"` // Every locked repository sorted by Repository
+}
+
+// LockedRepo a repository in a Lockfile
+type LockedRepo struct {
+	Repository string `yaml:"repository"`        // Canonical reference without a version, `github.com/YourName/dotfiles`
+	URL        string `yaml:"url"`               // URL the repository was cloned from
+	Version    string `yaml:"version,omitempty"` // Tag, branch or commit that was requested, empty for the default branch
+	Commit     string `yaml:"commit"`            // Commit the repository was at
+}
+
+// Ref a reference to the locked commit of the repository
+func (locked *LockedRepo) Ref() (*RepoRef, error) {
+	repo, err := ParseRepoRef(locked.URL)
+	if err != nil {
+		return nil, err
+	}
+	repo.Version = locked.Commit
+	return repo, nil
+}
+
+// LockfilePath the path to the lockfile, `<config>/dots/dots.lock`
+func LockfilePath() (string, error) {
+	configDir, err := config.ResolvePlaceholder("config")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "dots", "dots.lock"), nil
+}
+
+// ReadLockfile reads the lockfile at path, if it doesn't exist an empty Lockfile is returned
+func ReadLockfile(path string) (*Lockfile, error) {
+	lock := &Lockfile{}
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile `%s`: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(lock); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse lockfile `%s`: %w", path, err)
+	}
+	return lock, nil
+}
+
+// Write writes the lockfile to path, replacing it at once so it's never partially written
+func (lock *Lockfile) Write(path string) error {
+	sort.Slice(lock.Repos, func(i, j int) bool { return lock.Repos[i].Repository < lock.Repos[j].Repository })
+	var contents bytes.Buffer
+	encoder := yaml.NewEncoder(&contents)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(lock); err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to mkdir %s: %w", filepath.Dir(path), err)
+	}
+	if err := writeFileAtomic(path, append([]byte(lockfileHeader+"\n"), contents.Bytes()...)); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// Find the locked repository that's the same repository as repo, regardless of version, nil if it isn't locked
+func (lock *Lockfile) Find(repo *RepoRef) *LockedRepo {
+	if i := lock.index(func(locked *RepoRef) bool { return locked.CachePath() == repo.CachePath() }); i != -1 {
+		return &lock.Repos[i]
+	}
+	return nil
+}
+
+// FindPath finds the locked repository at path in cache, nil if it isn't locked
+func (lock *Lockfile) FindPath(cache *Cache, path string) *LockedRepo {
+	if i := lock.index(func(locked *RepoRef) bool { return cache.Path(locked) == path }); i != -1 {
+		return &lock.Repos[i]
+	}
+	return nil
+}
+
+// Set locks repo at commit, replacing the repository if it's already locked
+//
+// A repository that's already locked keeps the version it was requested at when repo doesn't have one.
+func (lock *Lockfile) Set(repo *RepoRef, commit string) {
+	canonical := *repo
+	canonical.Version = ""
+	locked := LockedRepo{Repository: canonical.String(), URL: repo.URL, Version: repo.Version, Commit: commit}
+	if existing := lock.Find(repo); existing != nil {
+		if locked.Version == "" {
+			locked.Version = existing.Version
+		}
+		*existing = locked
+		return
+	}
+	lock.Repos = append(lock.Repos, locked)
+}
+
+// Remove removes repo from the lockfile, returns true if it was locked
+func (lock *Lockfile) Remove(repo *RepoRef) bool {
+	return lock.remove(func(locked *RepoRef) bool { return locked.CachePath() == repo.CachePath() })
+}
+
+// RemovePath removes the repository at path in cache from the lockfile, returns true if it was locked
+func (lock *Lockfile) RemovePath(cache *Cache, path string) bool {
+	return lock.remove(func(locked *RepoRef) bool { return cache.Path(locked) == path })
+}
+
+// Update sets the commit of every locked repository that was upgraded successfully
+func (lock *Lockfile) Update(cache *Cache, results []UpgradeResult) {
+	for _, result := range results {
+		if result.Err != nil || result.After == "" {
+			continue
+		}
+		if i := lock.index(func(locked *RepoRef) bool { return cache.Path(locked) == result.Path }); i != -1 {
+			lock.Repos[i].Commit = result.After
+		}
+	}
+}
+
+// Removes the first locked repository that matches
+func (lock *Lockfile) remove(match func(locked *RepoRef) bool) bool {
+	i := lock.index(match)
+	if i == -1 {
+		return false
+	}
+	lock.Repos = append(lock.Repos[:i], lock.Repos[i+1:]...)
+	return true
+}
+
+// Index of the first locked repository that matches, -1 if none do
+func (lock *Lockfile) index(match func(locked *RepoRef) bool) int {
+	for i := range lock.Repos {
+		if locked, err := ParseRepoRef(lock.Repos[i].URL); err == nil && match(locked) {
+			return i
+		}
+	}
+	return -1
+}
+
+// UpdateLockfile reads the lockfile at path, applies update to it and writes it back, while holding the
+// lock on state shared by every repository so updates from other processes aren't lost
+func (cache *Cache) UpdateLockfile(path string, update func(lock *Lockfile)) (err error) {
+	stateLock, err := cache.LockState()
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, &err)
+
+	lock, err := ReadLockfile(path)
+	if err != nil {
+		return err
+	}
+	update(lock)
+	return lock.Write(path)
+}
+
+// Commit the commit the repository at path is at, empty if it isn't a git repository
+func (cache *Cache) Commit(path string) (string, error) {
+	if _, err := os.Stat(filepath.Join(path, ".git")); os.IsNotExist(err) {
+		return "", nil
+	}
+	return head(path)
+}
+
+// Checkout checks out rev, a tag, branch or commit, in the git repository at path, fetching first if
+// rev isn't known locally
+//
+// The dots config MUST still be valid at rev, otherwise the previous commit is checked out again. Other
+// processes getting, upgrading or removing the same repository are waited for.
+func (cache *Cache) Checkout(path, rev string) (err error) {
+	lock, err := cache.lockRepo(path)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock, &err)
+
+	if err = cache.checkoutRev(path, rev); err != nil {
+		return err
+	}
+	return cache.index(path)
+}
+
+// Checks out rev in the git repository at path, see `Checkout`, without locking it or updating the index
+func (cache *Cache) checkoutRev(path, rev string) error {
+	if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+		return fmt.Errorf("can't check out `%s` in `%s`, only git repositories can be checked out at a version", rev, path)
+	}
+	previous, err := head(path)
+	if err != nil {
+		return err
+	}
+	if err = cache.fetchRev(path, path, rev); err != nil {
+		return err
+	}
+
+	if err = checkout(path, rev); err != nil {
+		return err
+	}
+	if err = applySparse(path); err == nil {
+		err = validateRepo(path, path)
+	}
+	if err != nil {
+		if syncErr := checkout(path, previous); syncErr != nil {
+			return fmt.Errorf("%v, then failed to check out the previous commit `%s`: %w", err, previous, syncErr)
+		}
+		if syncErr := applySparse(path); syncErr != nil {
+			return fmt.Errorf("%v, then failed to check out the previous commit `%s`: %w", err, previous, syncErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Fetches rev, a tag, branch or commit, into the git repository at path unless it's already there, repo
+// is how errors refer to it
+//
+// A shallow repository only fetches rev itself rather than its history.
+func (cache *Cache) fetchRev(path, repo, rev string) error {
+	if hasRev(path, rev) {
+		return nil
+	}
+	if !shallow(path) {
+		if err := cache.gitNetwork(path, repo, "fetch", "--quiet", "--tags", "origin"); err != nil {
+			return fmt.Errorf("`%s` isn't in `%s`: %w", rev, repo, err)
+		}
+		return nil
+	}
+
+	// Tried as a branch, then a tag and lastly a commit
+	refspecs := []string{"+refs/heads/" + rev + ":refs/remotes/origin/" + rev, "+refs/tags/" + rev + ":refs/tags/" + rev, rev}
+	var err error
+	for _, refspec := range refspecs {
+		if err = cache.gitNetwork(path, repo, "fetch", "--quiet", "--depth", "1", "origin", refspec); err == nil && hasRev(path, rev) {
+			return nil
+		}
+		var offlineErr *OfflineError
+		var timeoutErr *NetworkTimeoutError
+		if errors.As(err, &offlineErr) || errors.As(err, &timeoutErr) {
+			break
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("it wasn't found on `origin`")
+	}
+	return fmt.Errorf("`%s` isn't in `%s`: %w", rev, repo, err)
+}
+
+// Checks if rev is a commit in the git repository at path
+func hasRev(path, rev string) bool {
+	return exec.Command("git", "-C", path, "rev-parse", "--verify", "--quiet", rev+"^{commit}").Run() == nil
+}
+
+// Checks if the git repository at path is a shallow clone
+func shallow(path string) bool {
+	output, err := exec.Command("git", "-C", path, "rev-parse", "--is-shallow-repository").Output()
+	return err == nil && strings.TrimSpace(string(output)) == "true"
+}
+
+// Checks out rev in the git repository at dir, unlike `vcs.Cmd.TagSync` rev can be a commit and a branch
+// is checked out as a branch rather than a detached HEAD
+func checkout(dir, rev string) error {
+	if err := git(dir, "checkout", "--quiet", rev, "--"); err != nil {
+		return fmt.Errorf("failed to check out `%s` in `%s`: %w", rev, dir, err)
+	}
+	return nil
+}
+
+// Checks that the repository at path has a valid dots config in its root, name is how errors refer to it
+func validateRepo(path, name string) error {
+	if dotfiles, err := SparseDotfiles(path); err != nil || dotfiles != nil {
+		if err != nil {
+			return err
+		}
+		return validateSparse(path, name, dotfiles)
+	}
+	configPath, err := config.FindConfigIn(path)
+	if err != nil {
+		return fmt.Errorf("repository `%s` has no dots config in its root: %w", name, err)
+	}
+	if validationErr := config.Validate(configPath); validationErr != nil && validationErr.IsErr() {
+		return fmt.Errorf("repository `%s` has an invalid dots config: %w", name, validationErr)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package cache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-lock")
+	assert.NoErrorf(t, err, "failed to setup lock_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dots", "dots.lock")
+	lock, err := cache.ReadLockfile(path)
+	assert.NoError(t, err)
+	assert.Empty(t, lock.Repos)
+
+	zsh, err := cache.ParseRepoRef("NickHackman/zsh@v1.0")
+	assert.NoError(t, err)
+	dotfiles, err := cache.ParseRepoRef("gitlab.com/NickHackman/dotfiles")
+	assert.NoError(t, err)
+	lock.Set(zsh, "1111111111111111111111111111111111111111")
+	lock.Set(dotfiles, "2222222222222222222222222222222222222222")
+	assert.NoError(t, lock.Write(path))
+
+	lock, err = cache.ReadLockfile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.LockedRepo{
+		{Repository: "github.com/NickHackman/zsh", URL: "https://github.com/NickHackman/zsh", Version: "v1.0", Commit: "1111111111111111111111111111111111111111"},
+		{Repository: "gitlab.com/NickHackman/dotfiles", URL: "https://gitlab.com/NickHackman/dotfiles", Commit: "2222222222222222222222222222222222222222"},
+	}, lock.Repos)
+
+	// Another version of a locked repository replaces it
+	other, err := cache.ParseRepoRef("https://github.com/NickHackman/zsh@main")
+	assert.NoError(t, err)
+	locked := lock.Find(other)
+	assert.NotNil(t, locked)
+	assert.Equal(t, "v1.0", locked.Version)
+	lock.Set(other, "3333333333333333333333333333333333333333")
+	assert.Len(t, lock.Repos, 2)
+	ref, err := lock.Find(zsh).Ref()
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/NickHackman/zsh@3333333333333333333333333333333333333333", ref.String())
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	lock.Update(dotsCache, []cache.UpgradeResult{
+		{Path: dotsCache.Path(dotfiles), Before: "2222222222222222222222222222222222222222", After: "4444444444444444444444444444444444444444"},
+		{Path: dotsCache.Path(zsh), Before: "3333333333333333333333333333333333333333", Err: os.ErrNotExist},
+	})
+	assert.Equal(t, "4444444444444444444444444444444444444444", lock.Find(dotfiles).Commit)
+	assert.Equal(t, "3333333333333333333333333333333333333333", lock.Find(zsh).Commit)
+
+	// A reference without a version keeps the pinned version
+	bare, err := cache.ParseRepoRef("NickHackman/zsh")
+	assert.NoError(t, err)
+	lock.Set(bare, "5555555555555555555555555555555555555555")
+	assert.Equal(t, "main", lock.Find(zsh).Version)
+	assert.Equal(t, "5555555555555555555555555555555555555555", lock.Find(zsh).Commit)
+
+	assert.True(t, lock.Remove(zsh))
+	assert.False(t, lock.Remove(zsh))
+	assert.True(t, lock.RemovePath(dotsCache, dotsCache.Path(dotfiles)))
+	assert.Empty(t, lock.Repos)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("repositories:\n  - repo: NickHackman/zsh\n"), 0644))
+	_, err = cache.ReadLockfile(path)
+	assert.Error(t, err)
+}
+
+func TestCheckout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup lock_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source")
+	createRepo(t, source, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+	runGit(t, source, "tag", "v1.0")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(source, "zsh", ".zshrc"), []byte("export EDITOR=vim\n"), 0644))
+	runGit(t, source, "commit", "--quiet", "-am", "Use vim")
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+	path, err := dotsCache.Get(repo)
+	assert.NoError(t, err)
+	latest, err := dotsCache.Commit(path)
+	assert.NoError(t, err)
+
+	// Tags created after the clone are fetched
+	runGit(t, source, "tag", "v1.1")
+	assert.NoError(t, dotsCache.Checkout(path, "v1.1"))
+	commit, err := dotsCache.Commit(path)
+	assert.NoError(t, err)
+	assert.Equal(t, latest, commit)
+
+	assert.NoError(t, dotsCache.Checkout(path, "v1.0"))
+	contents, err := ioutil.ReadFile(filepath.Join(path, "zsh", ".zshrc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "export EDITOR=nvim\n", string(contents))
+	pinned, err := dotsCache.Commit(path)
+	assert.NoError(t, err)
+
+	// A repository checked out at a tag stays at it when upgraded
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(source, "zsh", ".zshrc"), []byte("export EDITOR=emacs\n"), 0644))
+	runGit(t, source, "commit", "--quiet", "-am", "Use emacs")
+	results, err := dotsCache.UpgradeAll(1)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+	assert.False(t, results[0].Upgraded())
+	assert.Equal(t, pinned, results[0].After)
+
+	// A version without a valid dots config is rejected, leaving the previous commit checked out
+	assert.NoError(t, os.Remove(filepath.Join(source, ".dots.yml")))
+	runGit(t, source, "commit", "--quiet", "-am", "Remove dots config")
+	runGit(t, source, "tag", "broken")
+	assert.Error(t, dotsCache.Checkout(path, "broken"))
+	commit, err = dotsCache.Commit(path)
+	assert.NoError(t, err)
+	assert.Equal(t, pinned, commit)
+
+	assert.Error(t, dotsCache.Checkout(path, "doesnt-exist"))
+
+	// Getting a locked commit on another machine
+	other := &cache.Cache{Dir: filepath.Join(dir, "other")}
+	repo.Version = pinned
+	path, err = other.Get(repo)
+	assert.NoError(t, err)
+	commit, err = other.Commit(path)
+	assert.NoError(t, err)
+	assert.Equal(t, pinned, commit)
+
+	// Getting another version of a cached repository checks it out
+	repo.Version = "v1.1"
+	path, err = other.Get(repo)
+	assert.NoError(t, err)
+	commit, err = other.Commit(path)
+	assert.NoError(t, err)
+	assert.Equal(t, latest, commit)
+}
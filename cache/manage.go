@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NickHackman/dots/config"
+)
+
+// RepoInfo information about a repository in the cache
+type RepoInfo struct {
+	Path      string             // Path to the repository
+	Name      string             // Path relative to Cache.Dir, using `/` as the separator, such as `github.com/YourName/dotfiles`
+	Commit    string             // Commit HEAD points to, empty if it isn't a git repository
+	Fetched   time.Time          // When the repository was last fetched, either downloaded or upgraded
+	Config    *config.DotsConfig // Dots config in the root of the repository, nil if ConfigErr is set
+	ConfigErr error              // Error that occurred finding or parsing the dots config
+}
+
+// NotCachedError is an error dictating that a repository isn't in the cache
+type NotCachedError struct {
+	Repo string // Repository as it was referenced
+	Path string // Path it would be at in the cache
+}
+
+// Error returns a String stating which repository isn't in the cache
+func (nce *NotCachedError) Error() string {
+	return fmt.Sprintf("repository `%s` isn't in the cache, it would be at `%s`", nce.Repo, nce.Path)
+}
+
+// Info information about the repository at path, which is expected to be one of `Repos`
+func (cache *Cache) Info(path string) (*RepoInfo, error) {
+	name, err := filepath.Rel(cache.Dir, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find `%s` relative to the cache `%s`: %w", path, cache.Dir, err)
+	}
+	info := &RepoInfo{Path: path, Name: filepath.ToSlash(name)}
+
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		if info.Commit, err = head(path); err != nil {
+			return nil, err
+		}
+	}
+	if info.Fetched, err = lastFetched(path); err != nil {
+		return nil, err
+	}
+
+	repoConf, err := cache.LoadConfigAt(path)
+	if err != nil {
+		info.ConfigErr = err
+		return info, nil
+	}
+	info.Config = repoConf.Config
+	return info, nil
+}
+
+// When the repository at path was last fetched, from the modification time of the file git writes when
+// fetching or the repository itself if it's never been fetched since it was downloaded
+func lastFetched(path string) (time.Time, error) {
+	for _, marker := range []string{filepath.Join(".git", "FETCH_HEAD"), filepath.Join(".git", "HEAD"), ""} {
+		stat, err := os.Stat(filepath.Join(path, marker))
+		if err == nil {
+			return stat.ModTime(), nil
+		} else if !os.IsNotExist(err) {
+			return time.Time{}, fmt.Errorf("failed to stat `%s`: %w", filepath.Join(path, marker), err)
+		}
+	}
+	return time.Time{}, fmt.Errorf("repository `%s` doesn't exist", path)
+}
+
+// Size the disk usage of every file inside of path in bytes, symbolic links aren't followed
+func Size(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read `%s`: %w", path, err)
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return size, err
+}
+
+// Remove removes a repository from the cache along with any directories left empty, such as
+// `github.com/YourName` after removing `github.com/YourName/dotfiles`
+func (cache *Cache) Remove(repo *RepoRef) error {
+	path := cache.Path(repo)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &NotCachedError{Repo: repo.String(), Path: path}
+	}
+	return cache.remove(path)
+}
+
+// Removes the repository at path, its entry in the index and any parent directories left empty up to Cache.Dir,
+// while holding its lock
+func (cache *Cache) remove(path string) (err error) {
+	lock, err := cache.lockRepo(path)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock, &err)
+
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove `%s`: %w", path, err)
+	}
+	if err := cache.unindex(path); err != nil {
+		return err
+	}
+	for parent := filepath.Dir(path); parent != cache.Dir && isWithin(cache.Dir, parent); parent = filepath.Dir(parent) {
+		// Fails once the directory isn't empty
+		if err := os.Remove(parent); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// Installed checks if any dotfile of the repository is installed, which is when an install path of the
+// dotfile is a symbolic link that resolves to inside of the repository
+func (info *RepoInfo) Installed() (bool, error) {
+	if info.Config == nil {
+		return false, info.ConfigErr
+	}
+	root, err := filepath.EvalSymlinks(info.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve `%s`: %w", info.Path, err)
+	}
+
+	for _, dot := range info.Config.Dotfiles {
+		paths, err := dot.InstallPaths()
+		if err != nil {
+			// Sources that don't exist were never installed
+			continue
+		}
+		for _, path := range paths {
+			if stat, err := os.Lstat(path); err != nil || stat.Mode()&os.ModeSymlink == 0 {
+				continue
+			}
+			if resolved, err := filepath.EvalSymlinks(path); err == nil && isWithin(root, resolved) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Prune removes every repository in the cache without an installed dotfile, see `RepoInfo.Installed`,
+// returning the repositories removed. If dryRun is set nothing is removed.
+//
+// Repositories in lock, which can be nil, are kept so the lockfile can always be reproduced, as are
+// repositories whose dots config can't be parsed, since whether they're installed is unknown.
+func (cache *Cache) Prune(lock *Lockfile, dryRun bool) ([]*RepoInfo, error) {
+	repos, err := cache.Repos()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []*RepoInfo
+	for _, path := range repos {
+		info, err := cache.Info(path)
+		if err != nil {
+			return pruned, err
+		}
+		if info.ConfigErr != nil || (lock != nil && lock.FindPath(cache, path) != nil) {
+			continue
+		}
+		installed, err := info.Installed()
+		if err != nil {
+			return pruned, err
+		}
+		if installed {
+			continue
+		}
+		if !dryRun {
+			if err = cache.remove(path); err != nil {
+				return pruned, err
+			}
+		}
+		pruned = append(pruned, info)
+	}
+
+	if !dryRun {
+		if err = os.RemoveAll(filepath.Join(cache.Dir, tmpDir)); err != nil {
+			return pruned, fmt.Errorf("failed to remove `%s`: %w", filepath.Join(cache.Dir, tmpDir), err)
+		}
+	}
+	return pruned, nil
+}
+
+// Checks if path is root or inside of root
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !filepath.IsAbs(rel) && (len(rel) < 3 || rel[:3] != ".."+string(os.PathSeparator))
+}
@@ -0,0 +1,99 @@
+package cache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManage(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup manage_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.NoError(t, err)
+
+	home := filepath.Join(dir, "home")
+	assert.NoError(t, os.Mkdir(home, 0755))
+	defer os.Unsetenv("DOTS_TEST_HOME")
+	assert.NoError(t, os.Setenv("DOTS_TEST_HOME", home))
+
+	dotsConfig := validDotsConfig + "    destination: ${DOTS_TEST_HOME}/zsh\n"
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	var repos []*cache.RepoRef
+	for _, name := range []string{"installed", "unused", "removed", "locked"} {
+		source := filepath.Join(dir, "repos", name)
+		createRepo(t, source, map[string]string{".dots.yml": dotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+		repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+		assert.NoError(t, err)
+		_, err = dotsCache.Get(repo)
+		assert.NoError(t, err)
+		repos = append(repos, repo)
+	}
+	installed, unused, removed, locked := dotsCache.Path(repos[0]), dotsCache.Path(repos[1]), dotsCache.Path(repos[2]), dotsCache.Path(repos[3])
+	assert.NoError(t, os.Symlink(filepath.Join(installed, "zsh"), filepath.Join(home, "zsh")))
+
+	info, err := dotsCache.Info(installed)
+	assert.NoError(t, err)
+	assert.Equal(t, installed, info.Path)
+	assert.Equal(t, filepath.ToSlash(filepath.Join("file", dir, "repos", "installed")), info.Name)
+	assert.Len(t, info.Commit, 40)
+	assert.False(t, info.Fetched.IsZero())
+	assert.NoError(t, info.ConfigErr)
+	assert.Equal(t, "NickHackman/dotfiles", info.Config.Name)
+	assert.Equal(t, "MIT", info.Config.License)
+
+	size, err := cache.Size(filepath.Join(installed, "zsh"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("export EDITOR=nvim\n")), size)
+
+	assert.NoError(t, dotsCache.Remove(repos[2]))
+	assert.NoDirExists(t, removed)
+	assert.IsType(t, &cache.NotCachedError{}, dotsCache.Remove(repos[2]))
+
+	// Locked repositories are kept even though nothing is installed from them
+	commit, err := dotsCache.Commit(locked)
+	assert.NoError(t, err)
+	lock := &cache.Lockfile{}
+	lock.Set(repos[3], commit)
+
+	pruned, err := dotsCache.Prune(lock, true)
+	assert.NoError(t, err)
+	if assert.Len(t, pruned, 1) {
+		assert.Equal(t, unused, pruned[0].Path)
+	}
+	assert.DirExists(t, unused)
+
+	pruned, err = dotsCache.Prune(lock, false)
+	assert.NoError(t, err)
+	assert.Len(t, pruned, 1)
+	assert.NoDirExists(t, unused)
+	assert.DirExists(t, installed)
+	assert.DirExists(t, locked)
+
+	pruned, err = dotsCache.Prune(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, pruned, 1)
+	assert.NoDirExists(t, locked)
+
+	assert.NoError(t, dotsCache.Remove(repos[0]))
+	entries, err := ioutil.ReadDir(dotsCache.Dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{".locks", "index.json"}, names, "removing every repository should leave no empty directories behind")
+	index, err := dotsCache.ReadIndex()
+	assert.NoError(t, err)
+	assert.Empty(t, index.Repos)
+}
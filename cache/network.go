@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// DefaultNetworkTimeout how long fetching a repository can take when Cache.NetworkTimeout isn't set
+const DefaultNetworkTimeout = 5 * time.Minute
+
+// OfflineError is an error dictating that a repository had to be fetched while the cache is offline
+type OfflineError struct {
+	Repo   string // Repository as it was referenced
+	Action string // What needed the network, such as `download`
+}
+
+// Error returns a String stating what couldn't be done to which repository while offline
+func (oe *OfflineError) Error() string {
+	return fmt.Sprintf("can't %s `%s` while offline", oe.Action, oe.Repo)
+}
+
+// NetworkTimeoutError is an error dictating that fetching a repository took longer than Cache.NetworkTimeout
+type NetworkTimeoutError struct {
+	Repo    string        // Repository as it was referenced
+	Command string        // Git command that timed out, such as `git fetch`
+	Timeout time.Duration // How long the command was given
+}
+
+// Error returns a String stating which command timed out and after how long
+func (nte *NetworkTimeoutError) Error() string {
+	return fmt.Sprintf("`%s` of `%s` timed out after %s, check your connection or use `--offline`", nte.Command, nte.Repo, nte.Timeout)
+}
+
+// InterruptedError is an error dictating that a git command was stopped by a signal, such as Ctrl-C
+type InterruptedError struct {
+	Repo    string    // Repository as it was referenced
+	Command string    // Git command that was interrupted, such as `git fetch`
+	Signal  os.Signal // Signal that was received
+}
+
+// Error returns a String stating which command was interrupted by which signal
+func (ie *InterruptedError) Error() string {
+	return fmt.Sprintf("`%s` of `%s` was stopped by signal %s", ie.Command, ie.Repo, ie.Signal)
+}
+
+// Expired checks if a repository fetched at fetched is older than Cache.TTL, and may be out of date
+func (cache *Cache) Expired(fetched time.Time) bool {
+	return cache.TTL > 0 && time.Since(fetched) > cache.TTL
+}
+
+// Runs a git command that accesses the network for repo in dir, or the working directory if dir is empty
+//
+// It fails immediately if the cache is offline and after Cache.NetworkTimeout otherwise. Git is never
+// allowed to prompt for credentials, which would wait forever when nothing reads the prompt.
+//
+// Git runs in its own process group, so the terminal doesn't send it Ctrl-C. Interrupts are caught while
+// it runs instead, its process group is killed and an `InterruptedError` is returned so the caller can
+// clean up before stopping.
+func (cache *Cache) gitNetwork(dir, repo string, args ...string) error {
+	if cache.Offline {
+		return &OfflineError{Repo: repo, Action: "fetch"}
+	}
+	timeout := cache.NetworkTimeout
+	if timeout <= 0 {
+		timeout = DefaultNetworkTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	gitArgs := args
+	if dir != "" {
+		gitArgs = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", gitArgs...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if os.Getenv("GIT_SSH_COMMAND") == "" && os.Getenv("GIT_SSH") == "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+
+	// Output is written to a file rather than a pipe, children of git such as `ssh` or `git-remote-https`
+	// would hold a pipe open after git is killed and waiting would outlast the timeout
+	output, err := ioutil.TempFile("", "dots-git-")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file for `git %s`: %w", args[0], err)
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+	cmd.Stdout, cmd.Stderr = output, output
+	setProcessGroup(cmd)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, interruptSignals...)
+	defer signal.Stop(signals)
+
+	var sig os.Signal
+	if err = cmd.Start(); err == nil {
+		sig, err = waitSignal(cmd, signals)
+	}
+	if sig != nil {
+		return &InterruptedError{Repo: repo, Command: "git " + args[0], Signal: sig}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		killProcessGroup(cmd)
+		return &NetworkTimeoutError{Repo: repo, Command: "git " + args[0], Timeout: timeout}
+	}
+	if err != nil {
+		contents, _ := ioutil.ReadFile(output.Name())
+		return fmt.Errorf("`git %s` failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(contents)))
+	}
+	return nil
+}
+
+// Waits for cmd, which has been started, to exit unless a signal is received first, in which case cmd
+// and its process group are killed and the signal is returned
+func waitSignal(cmd *exec.Cmd, signals <-chan os.Signal) (os.Signal, error) {
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return nil, err
+	case sig := <-signals:
+		killProcessGroup(cmd)
+		cmd.Process.Kill()
+		<-done
+		return sig, nil
+	}
+}
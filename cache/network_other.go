@@ -0,0 +1,18 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !illumos && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!illumos,!linux,!netbsd,!openbsd,!solaris
+
+package cache
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Signals that stop git
+var interruptSignals = []os.Signal{os.Interrupt}
+
+// Process groups aren't supported, only cmd itself is killed when it times out
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// Process groups aren't supported, cmd itself was already killed when it timed out
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,133 @@
+package cache_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffline(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup network_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source")
+	createRepo(t, source, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache"), Offline: true}
+	_, err = dotsCache.Get(repo)
+	var offlineErr *cache.OfflineError
+	assert.True(t, errors.As(err, &offlineErr))
+
+	dotsCache.Offline = false
+	path, err := dotsCache.Get(repo)
+	assert.NoError(t, err)
+
+	// Repositories already in the cache can still be used
+	dotsCache.Offline = true
+	_, err = dotsCache.Get(repo)
+	assert.NoError(t, err)
+
+	runGit(t, source, "tag", "v1.0")
+	err = dotsCache.Checkout(path, "v1.0")
+	assert.True(t, errors.As(err, &offlineErr))
+
+	results, err := dotsCache.UpgradeAll(1)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.True(t, errors.As(results[0].Err, &offlineErr))
+	}
+}
+
+func TestNetworkTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("a fake git is written as a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup network_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	// Git hangs in a child that holds its output open, such as `ssh` waiting on an unreachable host
+	bin := filepath.Join(dir, "bin")
+	assert.NoError(t, os.Mkdir(bin, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(bin, "git"), []byte("#!/bin/sh\nsleep 20\n"), 0755))
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	assert.NoError(t, os.Setenv("PATH", bin+string(os.PathListSeparator)+path))
+
+	repo, err := cache.ParseRepoRef("https://example.com/YourName/dotfiles")
+	assert.NoError(t, err)
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache"), NetworkTimeout: time.Second}
+	start := time.Now()
+	_, err = dotsCache.Get(repo)
+	var timeoutErr *cache.NetworkTimeoutError
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.True(t, time.Since(start) < 10*time.Second, "took %s to time out", time.Since(start))
+}
+
+func TestNetworkInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("a fake git is written as a shell script and interrupts can't be sent")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup network_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	// Git writes a file once it's started, so the interrupt is only sent while it's caught
+	bin, started := filepath.Join(dir, "bin"), filepath.Join(dir, "started")
+	assert.NoError(t, os.Mkdir(bin, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(bin, "git"), []byte("#!/bin/sh\ntouch "+started+"\nsleep 20\n"), 0755))
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	assert.NoError(t, os.Setenv("PATH", bin+string(os.PathListSeparator)+path))
+
+	go func() {
+		for !exists(started) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if process, err := os.FindProcess(os.Getpid()); err == nil {
+			process.Signal(os.Interrupt)
+		}
+	}()
+
+	repo, err := cache.ParseRepoRef("https://example.com/YourName/dotfiles")
+	assert.NoError(t, err)
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	start := time.Now()
+	_, err = dotsCache.Get(repo)
+	var interruptErr *cache.InterruptedError
+	if assert.True(t, errors.As(err, &interruptErr)) {
+		assert.Equal(t, os.Interrupt, interruptErr.Signal)
+	}
+	assert.True(t, time.Since(start) < 10*time.Second, "took %s to stop", time.Since(start))
+
+	// The temporary directory git was cloning into is removed
+	files, err := ioutil.ReadDir(filepath.Join(dotsCache.Dir, ".tmp"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestExpired(t *testing.T) {
+	dotsCache := &cache.Cache{}
+	assert.False(t, dotsCache.Expired(time.Now().Add(-24*365*time.Hour)), "without a TTL nothing expires")
+
+	dotsCache.TTL = time.Hour
+	assert.False(t, dotsCache.Expired(time.Now().Add(-time.Minute)))
+	assert.True(t, dotsCache.Expired(time.Now().Add(-2*time.Hour)))
+}
@@ -0,0 +1,25 @@
+//go:build aix || darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd illumos linux netbsd openbsd solaris
+
+package cache
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Signals forwarded to git, which isn't in the terminal's process group
+var interruptSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// Starts cmd in its own process group, so the processes it starts can be killed along with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Kills every process left in the process group of cmd, which has already been started
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultHost the host repositories are on when only `YourName/dotfiles` is written
+const DefaultHost = "github.com"
+
+var (
+	// Matches remotes written as `user@host:path`
+	scpRegexp = regexp.MustCompile(`^([\w.-]+)@([\w.-]+):(.+)$`)
+	// Matches a host, optionally with a port
+	hostRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?(:[0-9]+)?$`)
+	// Matches a single element of a repository's path on its host
+	segmentRegexp = regexp.MustCompile(`^[A-Za-z0-9_.~-]+$`)
+	// Matches a tag, branch or commit, git's own rules are stricter
+	versionRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./+-]*$`)
+)
+
+// RefError is an error dictating that a repository reference couldn't be parsed
+type RefError struct {
+	Ref    string // Reference as it was written
+	Reason string // Reason it's invalid
+}
+
+// Error returns a String stating the reference and why it's invalid
+func (re *RefError) Error() string {
+	return fmt.Sprintf("invalid repository `%s`: %s", re.Ref, re.Reason)
+}
+
+// RepoRef a reference to a repository of dotfiles, either on a host or a local repository
+type RepoRef struct {
+	Host    string // Host, such as `github.com`, empty for a local repository
+	Path    string // Path on the host, such as `NickHackman/dotfiles`, or the absolute path of a local repository
+	Version string // Tag, branch or commit written after `@`, empty for the default branch
+	URL     string // URL to clone the repository from
+}
+
+// ParseRepoRef parses a reference to a repository in any of the forms
+//
+// YourName/dotfiles                          - shorthand for a repository on `github.com`
+// gitlab.com/YourName/dotfiles               - a repository on a host, cloned over HTTPS
+// https://gitlab.com/YourName/dotfiles.git   - a URL using `https`, `http`, `ssh` or `git`
+// git@gitlab.com:YourName/dotfiles.git       - an SSH remote
+// file:///srv/repos/dotfiles                 - a local repository, `/srv/repos/dotfiles` and `./dotfiles` as well
+//
+// Any of these can be followed by `@version` for a tag, branch or commit, such as `YourName/dotfiles@v1.2`.
+// Paths on a host CANNOT contain `.` or `..` elements, so a reference can't escape its directory in the cache.
+func ParseRepoRef(ref string) (*RepoRef, error) {
+	refErr := &RefError{Ref: ref}
+	if ref == "" || strings.TrimSpace(ref) != ref {
+		refErr.Reason = "is blank or has surrounding whitespace"
+		return nil, refErr
+	}
+	if strings.ContainsAny(ref, "\x00\n\r\t ") {
+		refErr.Reason = "contains whitespace or a NUL"
+		return nil, refErr
+	}
+
+	var repo *RepoRef
+	var err error
+	switch {
+	case strings.HasPrefix(ref, "file://"):
+		repo, err = parseFileRef(refErr, ref)
+	case strings.Contains(ref, "://"):
+		repo, err = parseURLRef(refErr, ref)
+	case scpRegexp.MatchString(ref):
+		repo, err = parseSCPRef(refErr, ref)
+	case strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../") || filepath.IsAbs(ref):
+		repo, err = parseLocalRef(refErr, ref)
+	default:
+		repo, err = parseHostRef(refErr, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if repo.Version != "" && (!versionRegexp.MatchString(repo.Version) || strings.Contains(repo.Version, "..")) {
+		refErr.Reason = fmt.Sprintf("version `%s` isn't a valid tag, branch or commit", repo.Version)
+		return nil, refErr
+	}
+	return repo, nil
+}
+
+// Splits a version off of the end of s at its first `@`
+func splitVersion(refErr *RefError, s string) (string, string, error) {
+	i := strings.IndexByte(s, '@')
+	if i == -1 {
+		return s, "", nil
+	}
+	if i == len(s)-1 {
+		refErr.Reason = "has an `@` without a version after it"
+		return "", "", refErr
+	}
+	return s[:i], s[i+1:], nil
+}
+
+// Parses `YourName/dotfiles` or `host/YourName/dotfiles`
+func parseHostRef(refErr *RefError, ref string) (*RepoRef, error) {
+	rest, version, err := splitVersion(refErr, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	host, repoPath := DefaultHost, rest
+	if first := strings.IndexByte(rest, '/'); first != -1 && strings.ContainsAny(rest[:first], ".:") {
+		host, repoPath = rest[:first], rest[first+1:]
+	} else if strings.Count(rest, "/") != 1 {
+		refErr.Reason = "isn't `YourName/dotfiles`, a host followed by a path or a URL"
+		return nil, refErr
+	}
+	return newRemoteRef(refErr, host, repoPath, version, "https://%s/%s")
+}
+
+// Parses a URL such as `https://host/YourName/dotfiles.git`
+func parseURLRef(refErr *RefError, ref string) (*RepoRef, error) {
+	schemeEnd := strings.Index(ref, "://") + len("://")
+	pathStart := strings.IndexByte(ref[schemeEnd:], '/')
+	if pathStart == -1 {
+		refErr.Reason = "is a URL without a path"
+		return nil, refErr
+	}
+	pathStart += schemeEnd
+
+	repoPath, version, err := splitVersion(refErr, ref[pathStart:])
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := url.Parse(ref[:pathStart] + repoPath)
+	if err != nil {
+		refErr.Reason = fmt.Sprintf("isn't a valid URL: %v", err)
+		return nil, refErr
+	}
+	switch parsed.Scheme {
+	case "https", "http", "ssh", "git":
+	default:
+		refErr.Reason = fmt.Sprintf("uses scheme `%s`, expected one of `https`, `http`, `ssh`, `git` or `file`", parsed.Scheme)
+		return nil, refErr
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		refErr.Reason = "is a URL with a query or fragment"
+		return nil, refErr
+	}
+
+	repo, err := newRemoteRef(refErr, parsed.Host, parsed.Path, version, "")
+	if err != nil {
+		return nil, err
+	}
+	repo.URL = parsed.String()
+	return repo, nil
+}
+
+// Parses an SSH remote such as `git@host:YourName/dotfiles.git`
+func parseSCPRef(refErr *RefError, ref string) (*RepoRef, error) {
+	match := scpRegexp.FindStringSubmatch(ref)
+	repoPath, version, err := splitVersion(refErr, match[3])
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := newRemoteRef(refErr, match[2], repoPath, version, "")
+	if err != nil {
+		return nil, err
+	}
+	repo.URL = fmt.Sprintf("%s@%s:%s", match[1], match[2], repoPath)
+	return repo, nil
+}
+
+// Checks and normalizes a repository on a host, if urlFormat isn't empty it's formatted with the
+// host and path to create the URL
+func newRemoteRef(refErr *RefError, host, repoPath, version, urlFormat string) (*RepoRef, error) {
+	host = strings.ToLower(host)
+	if !hostRegexp.MatchString(host) {
+		refErr.Reason = fmt.Sprintf("host `%s` isn't a valid host", host)
+		return nil, refErr
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	segments := strings.Split(repoPath, "/")
+	if len(segments) < 2 {
+		refErr.Reason = fmt.Sprintf("path `%s` should be at least `YourName/dotfiles`", repoPath)
+		return nil, refErr
+	}
+	for _, segment := range segments {
+		if segment == "." || segment == ".." || !segmentRegexp.MatchString(segment) {
+			refErr.Reason = fmt.Sprintf("path `%s` has an invalid element `%s`", repoPath, segment)
+			return nil, refErr
+		}
+	}
+
+	repo := &RepoRef{Host: host, Path: repoPath, Version: version}
+	if urlFormat != "" {
+		repo.URL = fmt.Sprintf(urlFormat, host, repoPath)
+	}
+	return repo, nil
+}
+
+// Parses `file:///srv/repos/dotfiles`
+func parseFileRef(refErr *RefError, ref string) (*RepoRef, error) {
+	rest := strings.TrimPrefix(ref, "file://")
+	if strings.HasPrefix(rest, "localhost/") {
+		rest = strings.TrimPrefix(rest, "localhost")
+	}
+	if !strings.HasPrefix(rest, "/") {
+		refErr.Reason = "is a `file://` URL that isn't an absolute path, such as `file:///srv/repos/dotfiles`"
+		return nil, refErr
+	}
+
+	repoPath, version, err := splitVersion(refErr, rest)
+	if err != nil {
+		return nil, err
+	}
+	unescaped, err := url.PathUnescape(repoPath)
+	if err != nil {
+		refErr.Reason = fmt.Sprintf("isn't a valid URL: %v", err)
+		return nil, refErr
+	}
+	return newLocalRef(filepath.FromSlash(unescaped), version), nil
+}
+
+// Parses a path to a local repository, `/srv/repos/dotfiles` or `./dotfiles`
+func parseLocalRef(refErr *RefError, ref string) (*RepoRef, error) {
+	repoPath, version, err := splitVersion(refErr, ref)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(repoPath)
+	if err != nil {
+		refErr.Reason = fmt.Sprintf("can't be made absolute: %v", err)
+		return nil, refErr
+	}
+	return newLocalRef(abs, version), nil
+}
+
+// A local repository at the absolute path repoPath
+func newLocalRef(repoPath, version string) *RepoRef {
+	repoPath = filepath.Clean(repoPath)
+	fileURL := url.URL{Scheme: "file", Path: filepath.ToSlash(repoPath)}
+	if !strings.HasPrefix(fileURL.Path, "/") {
+		fileURL.Path = "/" + fileURL.Path
+	}
+	return &RepoRef{Path: repoPath, Version: version, URL: fileURL.String()}
+}
+
+// IsLocal checks if the repository is a local repository rather than on a host
+func (repo *RepoRef) IsLocal() bool {
+	return repo.Host == ""
+}
+
+// CachePath the path of the repository relative to a Cache's Dir, the same for every form of reference
+// to the same repository, `github.com/YourName/dotfiles` for `YourName/dotfiles`
+//
+// The port of a host isn't included, local repositories are inside of `file`.
+func (repo *RepoRef) CachePath() string {
+	if repo.IsLocal() {
+		local := strings.Replace(filepath.ToSlash(repo.Path), ":", "", 1)
+		return filepath.Join("file", filepath.FromSlash(local))
+	}
+	host := repo.Host
+	if colon := strings.IndexByte(host, ':'); colon != -1 {
+		host = host[:colon]
+	}
+	return filepath.Join(host, filepath.FromSlash(repo.Path))
+}
+
+// String the canonical form of the reference, `github.com/YourName/dotfiles@v1.2`
+func (repo *RepoRef) String() string {
+	canonical := path.Join(repo.Host, repo.Path)
+	if repo.IsLocal() {
+		canonical = repo.URL
+	}
+	if repo.Version != "" {
+		canonical += "@" + repo.Version
+	}
+	return canonical
+}
@@ -0,0 +1,114 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRepoRef(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoErrorf(t, err, "failed to setup ref_test.go testing can't get working directory: %w", err)
+
+	tests := []struct {
+		ref       string
+		repo      *cache.RepoRef
+		cachePath string
+		canonical string
+	}{
+		{
+			ref:       "NickHackman/dotfiles",
+			repo:      &cache.RepoRef{Host: "github.com", Path: "NickHackman/dotfiles", URL: "https://github.com/NickHackman/dotfiles"},
+			cachePath: filepath.Join("github.com", "NickHackman", "dotfiles"),
+			canonical: "github.com/NickHackman/dotfiles",
+		},
+		{
+			ref:       "NickHackman/dotfiles@v1.2",
+			repo:      &cache.RepoRef{Host: "github.com", Path: "NickHackman/dotfiles", Version: "v1.2", URL: "https://github.com/NickHackman/dotfiles"},
+			cachePath: filepath.Join("github.com", "NickHackman", "dotfiles"),
+			canonical: "github.com/NickHackman/dotfiles@v1.2",
+		},
+		{
+			ref:       "GitLab.com/group/sub/dotfiles@feature/bspwm",
+			repo:      &cache.RepoRef{Host: "gitlab.com", Path: "group/sub/dotfiles", Version: "feature/bspwm", URL: "https://gitlab.com/group/sub/dotfiles"},
+			cachePath: filepath.Join("gitlab.com", "group", "sub", "dotfiles"),
+			canonical: "gitlab.com/group/sub/dotfiles@feature/bspwm",
+		},
+		{
+			ref:       "https://github.com/NickHackman/dotfiles.git",
+			repo:      &cache.RepoRef{Host: "github.com", Path: "NickHackman/dotfiles", URL: "https://github.com/NickHackman/dotfiles.git"},
+			cachePath: filepath.Join("github.com", "NickHackman", "dotfiles"),
+			canonical: "github.com/NickHackman/dotfiles",
+		},
+		{
+			ref:       "ssh://git@git.example.com:2222/NickHackman/dotfiles.git@3f2a1c9",
+			repo:      &cache.RepoRef{Host: "git.example.com:2222", Path: "NickHackman/dotfiles", Version: "3f2a1c9", URL: "ssh://git@git.example.com:2222/NickHackman/dotfiles.git"},
+			cachePath: filepath.Join("git.example.com", "NickHackman", "dotfiles"),
+			canonical: "git.example.com:2222/NickHackman/dotfiles@3f2a1c9",
+		},
+		{
+			ref:       "git@github.com:NickHackman/dotfiles.git",
+			repo:      &cache.RepoRef{Host: "github.com", Path: "NickHackman/dotfiles", URL: "git@github.com:NickHackman/dotfiles.git"},
+			cachePath: filepath.Join("github.com", "NickHackman", "dotfiles"),
+			canonical: "github.com/NickHackman/dotfiles",
+		},
+		{
+			ref:       "git@github.com:NickHackman/dotfiles.git@main",
+			repo:      &cache.RepoRef{Host: "github.com", Path: "NickHackman/dotfiles", Version: "main", URL: "git@github.com:NickHackman/dotfiles.git"},
+			cachePath: filepath.Join("github.com", "NickHackman", "dotfiles"),
+			canonical: "github.com/NickHackman/dotfiles@main",
+		},
+		{
+			ref:       "file:///srv/repos/dotfiles@v1",
+			repo:      &cache.RepoRef{Path: filepath.FromSlash("/srv/repos/dotfiles"), Version: "v1", URL: "file:///srv/repos/dotfiles"},
+			cachePath: filepath.Join("file", "srv", "repos", "dotfiles"),
+			canonical: "file:///srv/repos/dotfiles@v1",
+		},
+		{
+			ref:       "./dotfiles",
+			repo:      &cache.RepoRef{Path: filepath.Join(wd, "dotfiles"), URL: "file://" + filepath.ToSlash(filepath.Join(wd, "dotfiles"))},
+			cachePath: filepath.Join("file", filepath.Join(wd, "dotfiles")),
+			canonical: "file://" + filepath.ToSlash(filepath.Join(wd, "dotfiles")),
+		},
+	}
+
+	for _, test := range tests {
+		repo, err := cache.ParseRepoRef(test.ref)
+		if !assert.NoErrorf(t, err, "ref `%s`", test.ref) {
+			continue
+		}
+		assert.Equalf(t, test.repo, repo, "ref `%s`", test.ref)
+		assert.Equalf(t, test.cachePath, repo.CachePath(), "ref `%s`", test.ref)
+		assert.Equalf(t, test.canonical, repo.String(), "ref `%s`", test.ref)
+	}
+}
+
+func TestParseRepoRefInvalid(t *testing.T) {
+	for _, ref := range []string{
+		"",
+		"dotfiles",
+		"a/b/c",
+		"NickHackman/dotfiles@",
+		"NickHackman/dotfiles@-oProxyCommand=x",
+		"NickHackman/dotfiles@v1..v2",
+		"../../etc/passwd@x y",
+		"github.com/NickHackman/../../../etc",
+		"github.com/NickHackman/./dotfiles",
+		"https://github.com/NickHackman/%2e%2e/dotfiles",
+		"https://github.com/NickHackman/dotfiles?ref=main",
+		"https://github.com",
+		"git@github.com:../dotfiles",
+		"ftp://example.com/NickHackman/dotfiles",
+		"file://relative/path",
+		"git@bad_host!:NickHackman/dotfiles",
+	} {
+		_, err := cache.ParseRepoRef(ref)
+		assert.Errorf(t, err, "ref `%s` should be invalid", ref)
+		if err != nil {
+			assert.IsType(t, &cache.RefError{}, err)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/NickHackman/dots/config"
+)
+
+// Patterns checked out in every sparse repository, every file in the root such as the dots config and
+// license along with the fragments in `.dots.d`
+var sparseBase = []string{"/*", "!/*/", "/.dots.d/"}
+
+// Git config key that records the dotfiles checked out in a sparse repository
+const sparseDotfilesKey = "dots.dotfile"
+
+// UnknownDotfileError is an error dictating that a dotfile was requested that isn't in a repository's dots config
+type UnknownDotfileError struct {
+	Repo string // Path to the repository
+	Name string // Name of the dotfile
+}
+
+// Error returns a String stating which dotfile isn't in which repository
+func (ude *UnknownDotfileError) Error() string {
+	return fmt.Sprintf("repository `%s` doesn't have a dotfile `%s`", ude.Repo, ude.Name)
+}
+
+// GetSparse fetches a repository into the cache the same as `Get`, but only checks out the files in its
+// root, such as its dots config, and the sources of dotfiles, see `Widen` to check out more later
+//
+// If the repository is already a hit the dotfiles are checked out with `Widen`, along with its version
+// if it has one.
+func (cache *Cache) GetSparse(repo *RepoRef, dotfiles ...string) (string, error) {
+	if len(dotfiles) == 0 {
+		return "", fmt.Errorf("a sparse checkout of `%s` needs at least one dotfile", repo)
+	}
+	return cache.get(repo, dotfiles)
+}
+
+// Widen checks out the sources of dotfiles in the sparse repository at path as well, without any
+// dotfiles every file is checked out and the repository is no longer sparse
+//
+// A repository that isn't sparse already has every file checked out, so it's left as is.
+func (cache *Cache) Widen(path string, dotfiles ...string) (err error) {
+	lock, err := cache.lockRepo(path)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock, &err)
+
+	if err = cache.widen(path, dotfiles); err != nil {
+		return err
+	}
+	return cache.index(path)
+}
+
+// Widens the sparse repository at path, see `Widen`, without locking it
+func (cache *Cache) widen(path string, dotfiles []string) error {
+	current, err := SparseDotfiles(path)
+	if err != nil || current == nil {
+		return err
+	}
+
+	if len(dotfiles) == 0 {
+		if err = readSparse(path, []string{"/*"}); err != nil {
+			return err
+		}
+		unsetSparseDotfiles(path)
+		return git(path, "config", "core.sparseCheckout", "false")
+	}
+
+	widened := append([]string{}, current...)
+	for _, name := range dotfiles {
+		if !contains(widened, name) {
+			widened = append(widened, name)
+		}
+	}
+	if err = setSparse(path, widened); err != nil {
+		if restoreErr := setSparse(path, current); restoreErr != nil {
+			return fmt.Errorf("%v, then failed to check out the previous dotfiles: %w", err, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// SparseDotfiles returns the dotfiles checked out in the repository at path, nil if it isn't sparse
+func SparseDotfiles(path string) ([]string, error) {
+	output, err := exec.Command("git", "-C", path, "config", "--bool", "core.sparseCheckout").Output()
+	if err != nil || strings.TrimSpace(string(output)) != "true" {
+		return nil, nil
+	}
+	// Exits 1 when there aren't any, such as while a sparse repository is being fetched
+	output, _ = exec.Command("git", "-C", path, "config", "--get-all", sparseDotfilesKey).Output()
+	dotfiles := []string{}
+	for _, name := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if name != "" {
+			dotfiles = append(dotfiles, name)
+		}
+	}
+	return dotfiles, nil
+}
+
+// Makes the repository at path a sparse checkout of only dotfiles, recording them
+func setSparse(path string, dotfiles []string) error {
+	if err := git(path, "config", "core.sparseCheckout", "true"); err != nil {
+		return err
+	}
+	unsetSparseDotfiles(path)
+	for _, name := range dotfiles {
+		if err := git(path, "config", "--add", sparseDotfilesKey, name); err != nil {
+			return err
+		}
+	}
+	return applySparse(path)
+}
+
+// Removes the dotfiles recorded in the repository at path, git exits 5 when there aren't any so it isn't an error
+func unsetSparseDotfiles(path string) {
+	exec.Command("git", "-C", path, "config", "--unset-all", sparseDotfilesKey).Run()
+}
+
+// Checks out the files in the root of the sparse repository at path, then the includes of its dots
+// config and the sources of its recorded dotfiles
+//
+// Called after HEAD changes as well, since the dots config may have moved the sources.
+func applySparse(path string) error {
+	dotfiles, err := SparseDotfiles(path)
+	if err != nil || dotfiles == nil {
+		return err
+	}
+	if err = readSparse(path, sparseBase); err != nil {
+		return err
+	}
+
+	configPath, err := config.FindConfigIn(path)
+	if err != nil {
+		return err
+	}
+	includes, err := config.Includes(configPath)
+	if err != nil {
+		return err
+	}
+	patterns := append([]string{}, sparseBase...)
+	for _, include := range includes {
+		if filepath.IsAbs(include) {
+			if include, err = filepath.Rel(path, include); err != nil {
+				return fmt.Errorf("failed to find `%s` relative to `%s`: %w", include, path, err)
+			}
+		}
+		// Include patterns are globs relative to the root, which sparse checkout patterns are as well
+		patterns = append(patterns, "/"+strings.TrimPrefix(filepath.ToSlash(filepath.Clean(include)), "/"))
+	}
+	if len(includes) != 0 {
+		if err = readSparse(path, patterns); err != nil {
+			return err
+		}
+	}
+
+	dotsConf, err := config.ParseFile(configPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range dotfiles {
+		dot := findDotfile(dotsConf, name)
+		if dot == nil {
+			return &UnknownDotfileError{Repo: path, Name: name}
+		}
+		source, err := filepath.Rel(path, dot.Source)
+		if err != nil {
+			return fmt.Errorf("failed to find `%s` relative to `%s`: %w", dot.Source, path, err)
+		}
+		if source == "." {
+			patterns = append(patterns, "/*")
+			continue
+		}
+		patterns = append(patterns, "/"+escapePattern(filepath.ToSlash(source)))
+	}
+	return readSparse(path, patterns)
+}
+
+// Writes patterns as the sparse checkout patterns of the repository at path and updates the working tree
+// to match them
+func readSparse(path string, patterns []string) error {
+	gitDir, err := exec.Command("git", "-C", path, "rev-parse", "--git-dir").Output()
+	if err != nil {
+		return fmt.Errorf("failed to find the git directory of `%s`: %w", path, err)
+	}
+	dir := strings.TrimSpace(string(gitDir))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(path, dir)
+	}
+
+	info := filepath.Join(dir, "info")
+	if err = os.MkdirAll(info, 0755); err != nil {
+		return fmt.Errorf("failed to mkdir %s: %w", info, err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(info, "sparse-checkout"), []byte(strings.Join(patterns, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write the sparse checkout patterns of `%s`: %w", path, err)
+	}
+	return git(path, "read-tree", "-mu", "HEAD")
+}
+
+// Checks that the sparse repository at path has a valid dots config in its root and that the source of
+// every dotfile checked out exists, name is how errors refer to it
+func validateSparse(path, name string, dotfiles []string) error {
+	configPath, err := config.FindConfigIn(path)
+	if err != nil {
+		return fmt.Errorf("repository `%s` has no dots config in its root: %w", name, err)
+	}
+	// The sources of dotfiles that aren't checked out are missing
+	userConf := &config.UserConfig{Rules: map[string]config.Severity{"source-missing": config.SeverityOff}}
+	if validationErr := config.ValidateWith(configPath, userConf); validationErr != nil && validationErr.IsErr() {
+		return fmt.Errorf("repository `%s` has an invalid dots config: %w", name, validationErr)
+	}
+
+	dotsConf, err := config.ParseFile(configPath)
+	if err != nil {
+		return fmt.Errorf("repository `%s` has an invalid dots config: %w", name, err)
+	}
+	for _, dotName := range dotfiles {
+		dot := findDotfile(dotsConf, dotName)
+		if dot == nil {
+			return &UnknownDotfileError{Repo: name, Name: dotName}
+		}
+		if _, err := os.Stat(dot.Source); err != nil {
+			return fmt.Errorf("repository `%s` dotfile `%s` source `%s` doesn't exist", name, dot.Name, dot.Source)
+		}
+	}
+	return nil
+}
+
+// The dotfile in dotsConf named name, nil if there isn't one
+func findDotfile(dotsConf *config.DotsConfig, name string) *config.Dotfile {
+	for i := range dotsConf.Dotfiles {
+		if dotsConf.Dotfiles[i].Name == name {
+			return &dotsConf.Dotfiles[i]
+		}
+	}
+	return nil
+}
+
+// Escapes the characters that are special in a sparse checkout pattern, so path is matched literally
+func escapePattern(path string) string {
+	var escaped strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`\*?[!#`, r) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// Checks if values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
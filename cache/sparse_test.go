@@ -0,0 +1,178 @@
+package cache_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+const sparseDotsConfig = `version: 1
+name: NickHackman/dotfiles
+license: MIT
+dotfiles:
+  - name: zsh
+    description: Z shell
+  - name: nvim
+    description: Neovim
+`
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestGetSparse(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup sparse_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source")
+	createRepo(t, source, map[string]string{
+		".dots.yml":      sparseDotsConfig,
+		"LICENSE":        "MIT\n",
+		"zsh/.zshrc":     "export EDITOR=nvim\n",
+		"nvim/init.lua":  "vim.o.number = true\n",
+		"other/file.txt": "not a dotfile\n",
+	})
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+
+	_, err = dotsCache.GetSparse(repo, "doesnt-exist")
+	var unknownErr *cache.UnknownDotfileError
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.False(t, dotsCache.IsHit(repo.CachePath()))
+
+	path, err := dotsCache.GetSparse(repo, "zsh")
+	assert.NoError(t, err)
+	assert.True(t, exists(filepath.Join(path, ".dots.yml")))
+	assert.True(t, exists(filepath.Join(path, "LICENSE")))
+	assert.True(t, exists(filepath.Join(path, "zsh", ".zshrc")))
+	assert.False(t, exists(filepath.Join(path, "nvim")))
+	assert.False(t, exists(filepath.Join(path, "other")))
+
+	dotfiles, err := cache.SparseDotfiles(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zsh"}, dotfiles)
+	index, err := dotsCache.ReadIndex()
+	assert.NoError(t, err)
+	assert.Len(t, index.Repos, 1)
+	assert.Equal(t, []string{"zsh"}, index.Repos[0].Sparse)
+
+	// Getting it again with another dotfile widens it
+	_, err = dotsCache.GetSparse(repo, "nvim")
+	assert.NoError(t, err)
+	assert.True(t, exists(filepath.Join(path, "zsh", ".zshrc")))
+	assert.True(t, exists(filepath.Join(path, "nvim", "init.lua")))
+	assert.False(t, exists(filepath.Join(path, "other")))
+	dotfiles, err = cache.SparseDotfiles(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zsh", "nvim"}, dotfiles)
+
+	var widenErr *cache.UnknownDotfileError
+	assert.True(t, errors.As(dotsCache.Widen(path, "doesnt-exist"), &widenErr))
+
+	// A dotfile whose source moves stays checked out when upgraded
+	runGit(t, source, "mv", "zsh", "shell")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(source, ".dots.yml"), []byte(strings.Replace(sparseDotsConfig, "description: Z shell", "description: Z shell\n    source: shell", 1)), 0644))
+	runGit(t, source, "commit", "--quiet", "-am", "Move zsh")
+	assert.NoError(t, dotsCache.Upgrade(path))
+	assert.True(t, exists(filepath.Join(path, "shell", ".zshrc")))
+	assert.False(t, exists(filepath.Join(path, "zsh")))
+	assert.False(t, exists(filepath.Join(path, "other")))
+
+	// Widening without any dotfiles checks out every file
+	assert.NoError(t, dotsCache.Widen(path))
+	assert.True(t, exists(filepath.Join(path, "other", "file.txt")))
+	dotfiles, err = cache.SparseDotfiles(path)
+	assert.NoError(t, err)
+	assert.Nil(t, dotfiles)
+}
+
+func TestGetShallow(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup sparse_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source")
+	createRepo(t, source, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+	runGit(t, source, "tag", "v1.0")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(source, "zsh", ".zshrc"), []byte("export EDITOR=vim\n"), 0644))
+	runGit(t, source, "commit", "--quiet", "-am", "Use vim")
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache"), Shallow: true}
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+	path, err := dotsCache.Get(repo)
+	assert.NoError(t, err)
+
+	output, err := exec.Command("git", "-C", path, "rev-list", "--count", "HEAD").Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", strings.TrimSpace(string(output)))
+	index, err := dotsCache.ReadIndex()
+	assert.NoError(t, err)
+	assert.Len(t, index.Repos, 1)
+	assert.True(t, index.Repos[0].Shallow)
+
+	// Older versions are fetched when they're checked out
+	assert.NoError(t, dotsCache.Checkout(path, "v1.0"))
+	contents, err := ioutil.ReadFile(filepath.Join(path, "zsh", ".zshrc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "export EDITOR=nvim\n", string(contents))
+
+	// As well as when getting a version
+	other := &cache.Cache{Dir: filepath.Join(dir, "other"), Shallow: true}
+	repo.Version = "v1.0"
+	path, err = other.GetSparse(repo, "zsh")
+	assert.NoError(t, err)
+	contents, err = ioutil.ReadFile(filepath.Join(path, "zsh", ".zshrc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "export EDITOR=nvim\n", string(contents))
+}
+
+func TestGetSparseInstallChildren(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup sparse_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	// The children of `home` aren't checked out, so they can't be compared with other destinations
+	source := filepath.Join(dir, "source")
+	createRepo(t, source, map[string]string{
+		".dots.yml":      sparseDotsConfig + "  - name: home\n    description: Files in home\n    destination: ~/\n    install_children: true\n",
+		"zsh/.zshrc":     "export EDITOR=nvim\n",
+		"home/.profile":  "export PATH=$PATH:~/bin\n",
+		"nvim/init.lua":  "vim.o.number = true\n",
+		"other/file.txt": "not a dotfile\n",
+	})
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+	assert.NoError(t, err)
+	path, err := dotsCache.GetSparse(repo, "zsh")
+	assert.NoError(t, err)
+	assert.True(t, exists(filepath.Join(path, "zsh", ".zshrc")))
+	assert.False(t, exists(filepath.Join(path, "home")))
+
+	assert.NoError(t, dotsCache.Widen(path, "home"))
+	assert.True(t, exists(filepath.Join(path, "home", ".profile")))
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+// Directories that mark the root of a repository
+var vcsMarkers = []string{".git", ".hg", ".svn", ".bzr"}
+
+// UpgradeResult the result of upgrading a single repository
+type UpgradeResult struct {
+	Path    string   // Path to the repository
+	Before  string   // Commit before upgrading, empty if it isn't a git repository
+	After   string   // Commit after upgrading, empty if it isn't a git repository or upgrading failed
+	Changes []string // Commits between Before and After, newest first, as `3f2a1c9 Subject`
+	Err     error    // Error that occurred while upgrading, nil if it succeeded
+}
+
+// Upgraded checks if the repository is at a different commit after upgrading
+func (result *UpgradeResult) Upgraded() bool {
+	return result.Err == nil && result.Before != result.After
+}
+
+// Repos finds the root of every repository in the cache, sorted by path
+//
+// Repositories aren't searched for inside of other repositories, the temporary directory `Get` uses or
+// the directory of lock files.
+func (cache *Cache) Repos() ([]string, error) {
+	var repos []string
+	err := filepath.Walk(cache.Dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == cache.Dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read `%s`: %w", path, err)
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path == filepath.Join(cache.Dir, tmpDir) || path == filepath.Join(cache.Dir, locksDir) {
+			return filepath.SkipDir
+		}
+
+		for _, marker := range vcsMarkers {
+			if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+				repos = append(repos, path)
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	sort.Strings(repos)
+	return repos, err
+}
+
+// UpgradeAll upgrades every repository in the cache, see `Repos`, upgrading up to jobs repositories at once
+//
+// A failure to upgrade one repository doesn't stop the others, each result has its own Err. The results
+// are in the same order as `Repos`, an error is only returned if the repositories couldn't be found or
+// the index couldn't be updated, or an `InterruptedError` if an upgrade was interrupted. Repositories
+// that hadn't started upgrading when it was interrupted have it as their Err.
+func (cache *Cache) UpgradeAll(jobs int) ([]UpgradeResult, error) {
+	repos, err := cache.Repos()
+	if err != nil {
+		return nil, err
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	results := make([]UpgradeResult, len(repos))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var interrupted *InterruptedError
+	var interruptOnce sync.Once
+	stop := make(chan struct{})
+	for worker := 0; worker < jobs && worker < len(repos); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				select {
+				case <-stop:
+					results[i] = UpgradeResult{Path: repos[i], Err: interrupted}
+					continue
+				default:
+				}
+				results[i] = cache.upgrade(repos[i])
+				var interruptErr *InterruptedError
+				if errors.As(results[i].Err, &interruptErr) {
+					interruptOnce.Do(func() { interrupted = interruptErr; close(stop) })
+				}
+			}
+		}()
+	}
+	for i := range repos {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var upgraded []string
+	for _, result := range results {
+		if result.Err == nil {
+			upgraded = append(upgraded, result.Path)
+		}
+	}
+	if err = cache.index(upgraded...); err != nil || interrupted == nil {
+		return results, err
+	}
+	return results, interrupted
+}
+
+// Upgrade upgrades a specific repository expected to exist at path
+func (cache *Cache) Upgrade(path string) error {
+	if err := cache.upgrade(path).Err; err != nil {
+		return err
+	}
+	return cache.index(path)
+}
+
+// Upgrades the repository at path recording its commit before and after, while holding its lock
+func (cache *Cache) upgrade(path string) (result UpgradeResult) {
+	result.Path = path
+	lock, err := cache.lockRepo(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer releaseLock(lock, &result.Err)
+
+	cmd, root, err := vcs.FromDir(path, filepath.Dir(path))
+	if err != nil {
+		result.Err = fmt.Errorf("failed to find repository `%s`: %w", path, err)
+		return result
+	}
+	root = filepath.Join(filepath.Dir(path), filepath.FromSlash(root))
+
+	// x/tools only downloads changes, the working copy of a Mercurial repository wouldn't be updated
+	if cmd.Cmd != "git" {
+		result.Err = &UnsupportedVCSError{Repo: path, VCS: cmd.Name}
+		return result
+	}
+
+	if result.Before, err = head(root); err != nil {
+		result.Err = err
+		return result
+	}
+	if detached(root) {
+		// Checked out at a tag or commit, which stays put, fetch so a later `Checkout` doesn't have to
+		err = cache.gitNetwork(root, path, "fetch", "--quiet", "--tags", "origin")
+	} else {
+		err = cache.gitNetwork(root, path, "pull", "--quiet", "--ff-only", "--recurse-submodules")
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("failed to upgrade `%s`: %w", path, err)
+		return result
+	}
+	// The dots config may have moved the sources of the dotfiles checked out
+	if err = applySparse(root); err != nil {
+		result.Err = fmt.Errorf("failed to upgrade `%s`: %w", path, err)
+		return result
+	}
+
+	if result.After, result.Err = head(root); result.Err != nil || result.Before == result.After {
+		return result
+	}
+	output, err := exec.Command("git", "-C", root, "log", "--format=%h %s", result.Before+".."+result.After).Output()
+	if err != nil {
+		result.Err = fmt.Errorf("failed to list the commits upgraded in `%s`: %w", path, err)
+		return result
+	}
+	if changes := strings.TrimSpace(string(output)); changes != "" {
+		result.Changes = strings.Split(changes, "\n")
+	}
+	return result
+}
+
+// Checks if HEAD of the git repository at dir is detached, checked out at a tag or commit rather than a branch
+func detached(dir string) bool {
+	return exec.Command("git", "-C", dir, "symbolic-ref", "--quiet", "HEAD").Run() != nil
+}
+
+// Runs git in dir, including its output in the error if it fails
+func git(dir string, args ...string) error {
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("`git %s` failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// The commit HEAD of the git repository at dir points to
+func head(dir string) (string, error) {
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the current commit of `%s`: %w", dir, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
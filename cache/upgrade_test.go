@@ -0,0 +1,80 @@
+package cache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeAll(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-cache")
+	assert.NoErrorf(t, err, "failed to setup upgrade_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	dotsCache := &cache.Cache{Dir: filepath.Join(dir, "cache")}
+	results, err := dotsCache.UpgradeAll(2)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	var paths []string
+	for _, source := range []string{first, second} {
+		createRepo(t, source, map[string]string{".dots.yml": validDotsConfig, "zsh/.zshrc": "export EDITOR=nvim\n"})
+		repo, err := cache.ParseRepoRef("file://" + filepath.ToSlash(source))
+		assert.NoError(t, err)
+		path, err := dotsCache.Get(repo)
+		assert.NoError(t, err)
+		paths = append(paths, path)
+	}
+
+	// Neither of these are repositories, so they're skipped
+	assert.NoError(t, os.MkdirAll(filepath.Join(dotsCache.Dir, ".tmp", "get-1", "repo", ".git"), 0700))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dotsCache.Dir, "example.com", "empty"), 0700))
+	// A broken repository fails without stopping the others
+	broken := filepath.Join(dotsCache.Dir, "example.com", "NickHackman", "broken")
+	assert.NoError(t, os.MkdirAll(filepath.Join(broken, ".git"), 0700))
+	// Only git repositories can be upgraded
+	mercurial := filepath.Join(dotsCache.Dir, "example.com", "NickHackman", "mercurial")
+	assert.NoError(t, os.MkdirAll(filepath.Join(mercurial, ".hg"), 0700))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(second, "zsh", ".zshrc"), []byte("export EDITOR=vim\n"), 0644))
+	runGit(t, second, "commit", "--quiet", "-am", "Use vim")
+
+	repos, err := dotsCache.Repos()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{broken, mercurial, paths[0], paths[1]}, repos)
+
+	results, err = dotsCache.UpgradeAll(2)
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+
+	assert.Equal(t, broken, results[0].Path)
+	assert.Error(t, results[0].Err)
+	assert.False(t, results[0].Upgraded())
+
+	assert.Equal(t, mercurial, results[1].Path)
+	assert.Equal(t, &cache.UnsupportedVCSError{Repo: mercurial, VCS: "Mercurial"}, results[1].Err)
+
+	assert.Equal(t, paths[0], results[2].Path)
+	assert.NoError(t, results[2].Err)
+	assert.False(t, results[2].Upgraded())
+	assert.NotEmpty(t, results[2].After)
+
+	assert.Equal(t, paths[1], results[3].Path)
+	assert.NoError(t, results[3].Err)
+	assert.True(t, results[3].Upgraded())
+	assert.Len(t, results[3].Changes, 1)
+	assert.Regexp(t, "^[0-9a-f]+ Use vim$", results[3].Changes[0])
+	contents, err := ioutil.ReadFile(filepath.Join(paths[1], "zsh", ".zshrc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "export EDITOR=vim\n", string(contents))
+}
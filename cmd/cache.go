@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/cache"
+	"github.com/NickHackman/dots/config"
+)
+
+var (
+	pruneForce bool
+	offline    bool
+	shallow    bool
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage repositories of dotfiles in the cache",
+	Long:  `Manage repositories of dotfiles downloaded into the cache, '$XDG_CACHE_HOME/dots'.`,
+}
+
+// cacheListCmd represents the cache list command
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every repository in the cache",
+	Long: `List every repository in the cache along with when it was last fetched, its current commit
+and the name and license in its dots configuration file. Repositories fetched longer ago than the
+cache ttl, set by 'cache.ttl' in the user config, are warned about.
+
+The list is read from the cache index, repositories changed outside of dots are listed as they were
+when they were indexed along with a warning, run 'dots cache reindex' to update them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dotsCache := defaultCache()
+		index, err := dotsCache.ReadIndex()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		stale, err := dotsCache.Stale(index)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		var configErrs []string
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "REPOSITORY\tCOMMIT\tFETCHED\tNAME\tLICENSE")
+		for _, entry := range index.Repos {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", entry.Name, orDash(shortCommit(entry.Commit)), entry.Fetched.Format("2006-01-02 15:04"), orDash(entry.DotsName), orDash(entry.License))
+			if entry.ConfigErr != "" {
+				configErrs = append(configErrs, fmt.Sprintf("`%s` %s", entry.Name, entry.ConfigErr))
+			}
+		}
+		if err = writer.Flush(); err != nil {
+			return err
+		}
+		for _, configErr := range configErrs {
+			fmt.Printf("%s: %s\n", aurora.Yellow("Warning"), configErr)
+		}
+		for _, entry := range index.Repos {
+			warnExpired(dotsCache, entry.Name, entry.Fetched)
+		}
+		for _, repo := range stale {
+			fmt.Printf("%s: the index is out of date for `%s`, %s\n", aurora.Yellow("Warning"), repo.Name, repo.Reason)
+		}
+		if len(stale) != 0 {
+			fmt.Printf("%s: run `dots cache reindex` to update the index\n", aurora.Blue("Info"))
+		}
+		return nil
+	},
+}
+
+// cacheReindexCmd represents the cache reindex command
+var cacheReindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild the cache index from every repository in the cache",
+	Long: `Rebuild the cache index, '$XDG_CACHE_HOME/dots/index.json', from every repository in the cache.
+
+The index records the reference, URL, commit and dotfiles of every repository, it's kept up to date
+by dots, but repositories changed outside of dots leave it out of date.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := defaultCache().Reindex()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: indexed %d repositories\n", aurora.Green("Success"), len(index.Repos))
+		return nil
+	},
+}
+
+// cachePathCmd represents the cache path command
+var cachePathCmd = &cobra.Command{
+	Use:   "path [repository]",
+	Short: "Print the path to a repository in the cache, or the cache itself",
+	Long: `Print the path to a repository in the cache, a repository is written the same way as 'dots get'.
+Without a repository the path to the cache itself is printed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dotsCache := defaultCache()
+		if len(args) == 0 {
+			fmt.Println(dotsCache.Dir)
+			return nil
+		}
+
+		repo, err := cache.ParseRepoRef(args[0])
+		if err != nil {
+			return err
+		}
+		if !dotsCache.IsHit(repo.CachePath()) {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), &cache.NotCachedError{Repo: repo.String(), Path: dotsCache.Path(repo)})
+			os.Exit(1)
+		}
+		fmt.Println(dotsCache.Path(repo))
+		return nil
+	},
+}
+
+// cacheSizeCmd represents the cache size command
+var cacheSizeCmd = &cobra.Command{
+	Use:   "size",
+	Short: "Print the disk usage of the cache and each repository in it",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dotsCache := defaultCache()
+		repos, err := dotsCache.Repos()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		for _, path := range repos {
+			size, err := cache.Size(path)
+			if err != nil {
+				fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+				os.Exit(1)
+			}
+			name, err := filepath.Rel(dotsCache.Dir, path)
+			if err != nil {
+				name = path
+			}
+			fmt.Printf("%10s  %s\n", humanSize(size), filepath.ToSlash(name))
+		}
+
+		total, err := cache.Size(dotsCache.Dir)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		fmt.Printf("%10s  %s\n", humanSize(total), "total")
+		return nil
+	},
+}
+
+// cacheRemoveCmd represents the cache remove command
+var cacheRemoveCmd = &cobra.Command{
+	Use:   "remove <repository>...",
+	Short: "Remove repositories from the cache",
+	Long: `Remove repositories from the cache, a repository is written the same way as 'dots get'. Removed
+repositories are removed from the lockfile as well.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dotsCache := defaultCache()
+		for _, arg := range args {
+			repo, err := cache.ParseRepoRef(arg)
+			if err != nil {
+				return err
+			}
+			if err = dotsCache.Remove(repo); err != nil {
+				fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+				os.Exit(1)
+			}
+			updateLockfile(dotsCache, func(lock *cache.Lockfile) { lock.Remove(repo) })
+			fmt.Printf("%s: removed `%s`\n", aurora.Green("Remove"), repo)
+		}
+		return nil
+	},
+}
+
+// cacheWidenCmd represents the cache widen command
+var cacheWidenCmd = &cobra.Command{
+	Use:   "widen <repository> [dotfile]...",
+	Short: "Check out more dotfiles in a sparse repository in the cache",
+	Long: `Check out the sources of dotfiles in a repository downloaded with 'dots get --dotfile', which only
+checks out the files in its root and the sources of the dotfiles given. Without any dotfiles every
+file is checked out.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := cache.ParseRepoRef(args[0])
+		if err != nil {
+			return err
+		}
+		dotsCache := defaultCache()
+		path := dotsCache.Path(repo)
+		if !dotsCache.IsHit(repo.CachePath()) {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), &cache.NotCachedError{Repo: repo.String(), Path: path})
+			os.Exit(1)
+		}
+		if err = dotsCache.Widen(path, args[1:]...); err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: widened `%s`\n", aurora.Green("Success"), repo)
+		return nil
+	},
+}
+
+// cachePruneCmd represents the cache prune command
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove every repository in the cache without an installed dotfile",
+	Long: `Print every repository in the cache without an installed dotfile, use the '--force' flag to
+remove them.
+
+A dotfile is installed when where it installs to is a symbolic link into the repository. Repositories
+in the lockfile, '$XDG_CONFIG_HOME/dots/dots.lock', are kept so 'dots get --locked' always works, as
+are repositories whose dots configuration file can't be parsed. Run 'dots cache remove' to remove a
+repository from both the cache and the lockfile.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lock, err := cache.ReadLockfile(lockfile())
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		pruned, err := defaultCache().Prune(lock, !pruneForce)
+		for _, info := range pruned {
+			if pruneForce {
+				fmt.Printf("%s: removed `%s`\n", aurora.Green("Remove"), info.Name)
+			} else {
+				fmt.Printf("%s: would remove `%s`\n", aurora.Blue("Info"), info.Name)
+			}
+		}
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		if !pruneForce && len(pruned) != 0 {
+			fmt.Printf("%s: run `dots cache prune --force` to remove them\n", aurora.Blue("Info"))
+		}
+		return nil
+	},
+}
+
+// The default cache configured by the user config, `--offline` and `--shallow`, exits if it can't be created
+func defaultCache() *cache.Cache {
+	userConf, err := config.LoadUserConfig()
+	if err != nil {
+		fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+		os.Exit(1)
+	}
+	dotsCache, err := cache.DefaultCache()
+	if err != nil {
+		fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+		os.Exit(1)
+	}
+	dotsCache.Offline = offline || userConf.Cache.Offline
+	dotsCache.TTL = time.Duration(userConf.Cache.TTL)
+	dotsCache.NetworkTimeout = time.Duration(userConf.Cache.NetworkTimeout)
+	dotsCache.LockTimeout = time.Duration(userConf.Cache.LockTimeout)
+	dotsCache.Shallow = shallow || userConf.Cache.Shallow
+	dotsCache.OnWait = func(lock string, pid int) {
+		holder := "another process"
+		if pid != 0 {
+			holder = fmt.Sprintf("pid %d", pid)
+		}
+		fmt.Printf("%s: waiting for lock `%s` held by %s\n", aurora.Blue("Info"), lock, holder)
+	}
+	return dotsCache
+}
+
+// Updates the lockfile, see `cache.UpdateLockfile`, exits if it can't be updated
+func updateLockfile(dotsCache *cache.Cache, update func(lock *cache.Lockfile)) {
+	if err := dotsCache.UpdateLockfile(lockfile(), update); err != nil {
+		fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+		os.Exit(1)
+	}
+}
+
+// Warns that the repository name fetched at fetched is older than the cache's TTL, if it is
+func warnExpired(dotsCache *cache.Cache, name string, fetched time.Time) {
+	if !dotsCache.Expired(fetched) {
+		return
+	}
+	fmt.Printf("%s: `%s` was last fetched %s ago, longer than the cache ttl of %s, its dotfiles may be out of date\n",
+		aurora.Yellow("Warning"), name, humanDuration(time.Since(fetched)), humanDuration(dotsCache.TTL))
+}
+
+// Formats a duration in its largest whole unit, `3 days`
+func humanDuration(duration time.Duration) string {
+	units := []struct {
+		name string
+		size time.Duration
+	}{{"day", 24 * time.Hour}, {"hour", time.Hour}, {"minute", time.Minute}, {"second", time.Second}}
+	for _, unit := range units {
+		if count := int64(duration / unit.size); count >= 1 || unit.size == time.Second {
+			if count == 1 {
+				return fmt.Sprintf("1 %s", unit.name)
+			}
+			return fmt.Sprintf("%d %ss", count, unit.name)
+		}
+	}
+	return duration.String()
+}
+
+// Formats a number of bytes using binary units, `1.5 MiB`
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// Replaces an empty value with `-`
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cachePathCmd)
+	cacheCmd.AddCommand(cacheSizeCmd)
+	cacheCmd.AddCommand(cacheRemoveCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheWidenCmd)
+	cacheCmd.AddCommand(cacheReindexCmd)
+
+	cachePruneCmd.Flags().BoolVar(&pruneForce, "force", false, "Remove the repositories rather than only printing them")
+	cachePruneCmd.Flags().StringVar(&lockfilePath, "lockfile", "", "Path to the lockfile (default \"$XDG_CONFIG_HOME/dots/dots.lock\")")
+}
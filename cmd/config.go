@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/config"
+)
+
+var resolvedTo string
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect your dots configuration file",
+	Long:  `Inspect your dots configuration file and how dots finds it.`,
+}
+
+// configPathCmd represents the config path command
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path to the dots configuration file that would be used and why",
+	Long: `Print the path to the dots configuration file that would be used and why.
+
+The dots configuration file is chosen in order of priority
+
+1. The '--config' or '-c' flag
+2. The 'DOTS_CONFIG' environment variable
+3. The closest '.dots.yml', '.dots.yaml', '.dots.toml' or '.dots.json' starting at the current working
+   directory and progressing upwards until the root of the repository or the mount point
+   ('/' on unix systems). If a directory has more than one they take precedence in that order,
+   although having both '.dots.yml' and '.dots.yaml' is an error.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		located, err := locateConfig()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		fmt.Println(located.Path)
+		fmt.Printf("%s: %s\n", aurora.Blue("Info"), located.Reason())
+		for _, ignored := range located.Ignored {
+			fmt.Printf("%s: ignored `%s` which has a lower precedence\n", aurora.Blue("Info"), ignored)
+		}
+		return nil
+	},
+}
+
+// configResolvedCmd represents the config resolved command
+var configResolvedCmd = &cobra.Command{
+	Use:   "resolved",
+	Short: "Print your dots configuration file the way dots sees it",
+	Long: `Print your dots configuration file the way dots sees it.
+
+Included files are merged, 'defaults' are applied to every dotfile, and every source and destination
+is expanded, so each dotfile is printed with every field it'll be installed with.
+
+Use the '--to' flag to print it as 'yaml', 'toml' or 'json', by default it's printed as YAML.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := config.ParseFormat(resolvedTo)
+		if err != nil {
+			return err
+		}
+
+		path, err := resolveConfigPath()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		resolved, err := config.Resolve(path, format)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		fmt.Print(string(resolved))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configResolvedCmd)
+
+	configPathCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to `.dots.yml` file")
+	configResolvedCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to `.dots.yml` file")
+	configResolvedCmd.Flags().StringVar(&resolvedTo, "to", "yaml", "Format to print, one of `yaml`, `toml` or `json`")
+}
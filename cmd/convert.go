@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/config"
+)
+
+var (
+	convertTo     string
+	convertOutput string
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert your dots configuration file to another format",
+	Long: `Convert your dots configuration file to YAML, TOML or JSON.
+
+Dots configuration files can be written as '.dots.yml', '.dots.yaml', '.dots.toml' or '.dots.json',
+when a directory contains more than one they take precedence in that order.
+
+Convert will find the closest dots configuration file the same way as 'dots validate', and prints
+it converted to the format passed to '--to'. The converted file is migrated to the newest version,
+comments aren't preserved.
+
+Use the '--config' or '-c' flag in order to pass a path to a dots configuration file.
+Use the '--output' or '-o' flag to write the converted file instead of printing it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := config.ParseFormat(convertTo)
+		if err != nil {
+			return err
+		}
+
+		path, err := resolveConfigPath()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		converted, err := config.Convert(path, format)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		if convertOutput == "" {
+			fmt.Print(string(converted))
+			return nil
+		}
+		if err = ioutil.WriteFile(convertOutput, converted, 0644); err != nil {
+			fmt.Printf("%s: failed to write `%s`: %v\n", aurora.Red("Error"), convertOutput, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: converted `%s` to `%s`\n", aurora.Blue("Info"), path, convertOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to `.dots.yml` file")
+	convertCmd.Flags().StringVar(&convertTo, "to", "", "Format to convert to, one of `yaml`, `toml` or `json`")
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "Path to write the converted file to instead of printing it")
+	_ = convertCmd.MarkFlagRequired("to")
+}
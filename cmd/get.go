@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/cache"
+)
+
+var (
+	getLocked    bool
+	lockfilePath string
+	getDotfiles  []string
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get <repository>...",
+	Short: "Download repositories of dotfiles into the cache",
+	Long: `Download repositories of dotfiles into the cache, '$XDG_CACHE_HOME/dots'.
+
+A repository can be written as
+
+  NickHackman/dotfiles                       a repository on github.com
+  gitlab.com/NickHackman/dotfiles            a repository on another host
+  https://gitlab.com/NickHackman/dotfiles    a URL
+  git@gitlab.com:NickHackman/dotfiles.git    an SSH remote
+  file:///srv/repos/dotfiles                 a local repository, for use offline
+
+and followed by '@version' to download a tag, branch or commit, such as 'NickHackman/dotfiles@v1.2'.
+Repositories are cloned with git, only git repositories are supported, a local Mercurial, Subversion
+or Bazaar repository is rejected.
+Getting a repository that's already in the cache at another version checks out that version.
+
+A repository MUST have a valid dots configuration file in its root, otherwise it isn't added to
+the cache. Repositories that are already in the cache aren't downloaded again, unless they were
+fetched longer ago than the cache ttl, set by 'cache.ttl' in the user config, in which case they're
+upgraded.
+
+Use the '--shallow' flag, or 'cache.shallow' in the user config, to only download the latest commit
+of each branch, older versions are downloaded when they're checked out. Use the '--dotfile' flag to
+only check out the files in the root of a repository and the sources of the dotfiles given, getting
+it again with other dotfiles checks them out as well, see 'dots cache widen'.
+
+Use the '--offline' flag, or 'cache.offline' in the user config, to never access the network. Getting
+a repository that isn't in the cache then fails immediately.
+
+The commit of every repository is recorded in the lockfile, '$XDG_CONFIG_HOME/dots/dots.lock'. Use
+the '--locked' flag to download exactly the commits in the lockfile, such as on another machine,
+either of the repositories given or every repository in the lockfile.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if getLocked {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos := make([]*cache.RepoRef, len(args))
+		for i, arg := range args {
+			repo, err := cache.ParseRepoRef(arg)
+			if err != nil {
+				return err
+			}
+			repos[i] = repo
+		}
+
+		lockPath := lockfile()
+		if getLocked {
+			lock, err := cache.ReadLockfile(lockPath)
+			if err != nil {
+				fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+				os.Exit(1)
+			}
+			getLockedRepos(lock, repos)
+			return nil
+		}
+
+		dotsCache := defaultCache()
+		failed := false
+		commits := make(map[*cache.RepoRef]string, len(repos))
+		for _, repo := range repos {
+			path, err := getRepo(dotsCache, repo)
+			if err != nil {
+				fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+				failed = true
+				if interrupted(err) {
+					break
+				}
+				continue
+			}
+			commit, err := dotsCache.Commit(path)
+			if err != nil {
+				fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+				failed = true
+				continue
+			}
+			commits[repo] = commit
+		}
+		err := dotsCache.UpdateLockfile(lockPath, func(lock *cache.Lockfile) {
+			for _, repo := range repos {
+				if commit, ok := commits[repo]; ok {
+					lock.Set(repo, commit)
+				}
+			}
+		})
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			failed = true
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+// Gets repo into the cache, its version and `--dotfile` are checked out if it's already a hit
+func getRepo(dotsCache *cache.Cache, repo *cache.RepoRef) (string, error) {
+	hit := dotsCache.IsHit(repo.CachePath())
+	var path string
+	var err error
+	if len(getDotfiles) != 0 {
+		path, err = dotsCache.GetSparse(repo, getDotfiles...)
+	} else {
+		path, err = dotsCache.Get(repo)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case !hit:
+		fmt.Printf("%s: downloaded `%s` into `%s`\n", aurora.Green("Success"), repo, path)
+	case repo.Version != "":
+		fmt.Printf("%s: checked out `%s` in `%s`\n", aurora.Green("Success"), repo, path)
+	default:
+		return path, refreshRepo(dotsCache, repo, path)
+	}
+	return path, nil
+}
+
+// Upgrades repo, which is already in the cache at path, if it's older than the cache's TTL, when offline
+// it's only warned about
+func refreshRepo(dotsCache *cache.Cache, repo *cache.RepoRef, path string) error {
+	info, err := dotsCache.Info(path)
+	if err != nil {
+		return err
+	}
+	if !dotsCache.Expired(info.Fetched) {
+		fmt.Printf("%s: `%s` is already in the cache at `%s`\n", aurora.Blue("Info"), repo, path)
+		return nil
+	}
+	if dotsCache.Offline {
+		warnExpired(dotsCache, repo.String(), info.Fetched)
+		return nil
+	}
+	if err = dotsCache.Upgrade(path); err != nil {
+		return err
+	}
+	fmt.Printf("%s: upgraded `%s`, it was last fetched %s ago\n", aurora.Green("Upgrade"), repo, humanDuration(time.Since(info.Fetched)))
+	return nil
+}
+
+// Gets the locked commit of each of repos, or every locked repository if there are none, exits if any fail
+func getLockedRepos(lock *cache.Lockfile, repos []*cache.RepoRef) {
+	locked := lock.Repos
+	if len(repos) != 0 {
+		locked = make([]cache.LockedRepo, 0, len(repos))
+		for _, repo := range repos {
+			entry := lock.Find(repo)
+			if entry == nil {
+				fmt.Printf("%s: `%s` isn't in the lockfile, get it without `--locked` first\n", aurora.Red("Error"), repo)
+				os.Exit(1)
+			}
+			locked = append(locked, *entry)
+		}
+	}
+	if len(locked) == 0 {
+		fmt.Printf("%s: the lockfile is empty, there's nothing to get\n", aurora.Blue("Info"))
+		return
+	}
+
+	dotsCache := defaultCache()
+	failed := false
+	for _, entry := range locked {
+		repo, err := entry.Ref()
+		if err == nil {
+			var path string
+			if path, err = getRepo(dotsCache, repo); err == nil {
+				err = verifyCommit(dotsCache, path, entry)
+			}
+		}
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			failed = true
+			if interrupted(err) {
+				break
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// Checks if err is from git being interrupted, in which case the remaining repositories are skipped
+func interrupted(err error) bool {
+	var interruptErr *cache.InterruptedError
+	return errors.As(err, &interruptErr)
+}
+
+// Checks the repository at path is at exactly the locked commit
+func verifyCommit(dotsCache *cache.Cache, path string, entry cache.LockedRepo) error {
+	commit, err := dotsCache.Commit(path)
+	if err != nil {
+		return err
+	}
+	if commit != entry.Commit {
+		return fmt.Errorf("`%s` is at commit `%s`, but the lockfile has `%s`", entry.Repository, commit, entry.Commit)
+	}
+	return nil
+}
+
+// The path to the lockfile, `--lockfile` or the default path, exits if the default can't be found
+func lockfile() string {
+	if lockfilePath != "" {
+		return lockfilePath
+	}
+	path, err := cache.LockfilePath()
+	if err != nil {
+		fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+		os.Exit(1)
+	}
+	return path
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+
+	getCmd.Flags().BoolVar(&offline, "offline", false, "Never access the network, only use repositories already in the cache")
+	getCmd.Flags().BoolVar(&getLocked, "locked", false, "Get exactly the commits recorded in the lockfile")
+	getCmd.Flags().BoolVar(&shallow, "shallow", false, "Only download the latest commit of each branch")
+	getCmd.Flags().StringSliceVar(&getDotfiles, "dotfile", nil, "Only check out the sources of these dotfiles, can be repeated")
+	getCmd.Flags().StringVar(&lockfilePath, "lockfile", "", "Path to the lockfile (default \"$XDG_CONFIG_HOME/dots/dots.lock\")")
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/config"
+)
+
+var (
+	force       bool
+	printConfig bool
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init [directory]",
+	Short: "Create a .dots.yml configuration file for an existing repository of dotfiles",
+	Long: `Create a commented .dots.yml configuration file for an existing repository of dotfiles.
+
+Init walks the directory, by default the current working directory, and proposes a dotfile for
+directories of well-known applications such as 'nvim', 'bspwm', 'zsh' and 'alacritty' and for
+directories only containing dot-prefixed files such as '.xinitrc', which are installed to '~'.
+
+The license is detected from the license file (LICENSE, COPYING, ...) and the URL from the git remote.
+Review the proposed dotfiles and run 'dots validate' afterwards.
+
+Use the '--print' flag to print the configuration file instead of writing it.
+Use the '--force' flag to overwrite an existing dots configuration file.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+
+		if err = initConfig(dir); err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+// Infers a dots config for dir and writes it to `dir/.dots.yml`, or prints it if `--print` is set
+func initConfig(dir string) error {
+	path := filepath.Join(dir, ".dots.yml")
+	if !force && !printConfig {
+		existing, err := config.FindConfig(dir)
+		var ambiguous *config.AmbiguousConfigError
+		if errors.As(err, &ambiguous) {
+			existing, err = ambiguous.Paths[0], nil
+		}
+		if err == nil && filepath.Dir(existing) == dir {
+			return fmt.Errorf("`%s` already exists, use `--force` to overwrite it", existing)
+		}
+	}
+
+	dotsConfig, err := config.Infer(dir)
+	if err != nil {
+		return err
+	}
+	scaffolded, err := config.Scaffold(dotsConfig)
+	if err != nil {
+		return err
+	}
+	if printConfig {
+		fmt.Print(string(scaffolded))
+		return nil
+	}
+
+	for _, dot := range dotsConfig.Dotfiles {
+		fmt.Printf("%s: found dotfile `%s` installed to `%s`\n", aurora.Green("Init"), dot.Name, dot.Destination)
+	}
+	if dotsConfig.License == "" {
+		fmt.Printf("%s: no license file was recognized, license is left blank\n", aurora.Yellow("Warning"))
+	}
+	if dotsConfig.URL == "" {
+		fmt.Printf("%s: no git remote was found, URL is left blank\n", aurora.Yellow("Warning"))
+	}
+	if len(dotsConfig.Dotfiles) == 0 {
+		return errors.New("no dotfiles were recognized, write `.dots.yml` by hand using `dots.template.yml`")
+	}
+
+	if err = ioutil.WriteFile(path, scaffolded, 0644); err != nil {
+		return fmt.Errorf("failed to write `%s`: %w", path, err)
+	}
+	fmt.Printf("%s: wrote `%s`, review it and run `dots validate`\n", aurora.Blue("Info"), path)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing dots configuration file")
+	initCmd.Flags().BoolVar(&printConfig, "print", false, "Print the configuration file instead of writing it")
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/config"
+)
+
+// licenseCmd represents the license command
+var licenseCmd = &cobra.Command{
+	Use:   "license",
+	Short: "Show the license of a .dots.ya?ml configuration file",
+	Long: `Show the license of a .dots.ya?ml configuration file and metadata about every SPDX license it uses.
+
+License will find the closest '.dots.ya?ml' file the same way as 'dots validate', and compares the license
+to the license file (LICENSE, COPYING, ...) in the same directory if there is one.
+
+Use the '--config' or '-c' flag in order to pass a path to a dots configuration file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := resolveConfigPath()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		dotsConfig, err := config.ParseFile(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		expression, err := config.ParseLicense(dotsConfig.License)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s: %s\n", aurora.Bold("License"), expression.Expression)
+		if !expression.IsCanonical() {
+			fmt.Printf("%s: using SPDX license identifiers the license is `%s`\n", aurora.Yellow("Warning"), expression.Canonical)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, license := range expression.Licenses {
+			name := license.Name
+			switch {
+			case license.Custom:
+				name = "Custom license, not on the SPDX License List"
+			case license.Exception:
+				name = "License exception"
+			case name == "":
+				name = "-"
+			}
+			if license.Deprecated {
+				name = fmt.Sprintf("%s (deprecated)", name)
+			}
+			fmt.Fprintf(writer, "  %s\t%s\t%s\n", license.ID, name, license.URL())
+		}
+		writer.Flush()
+
+		detected, err := config.DetectLicense(filepath.Dir(path))
+		switch {
+		case err != nil:
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		case detected == nil:
+			fmt.Printf("%s: none\n", aurora.Bold("License file"))
+		case detected.ID == "":
+			fmt.Printf("%s: %s (unrecognized)\n", aurora.Bold("License file"), detected.Path)
+		default:
+			fmt.Printf("%s: %s (%s)\n", aurora.Bold("License file"), detected.Path, detected.ID)
+			if !expression.Includes(detected.ID) {
+				fmt.Printf("%s: license doesn't match the license file\n", aurora.Yellow("Warning"))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(licenseCmd)
+
+	licenseCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to `.dots.yml` file")
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/config"
+)
+
+var dryRun bool
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate your .dots.ya?ml configuration file to the newest version",
+	Long: `Migrate your .dots.ya?ml configuration file to the newest version of the dots configuration format.
+
+Dots reads configuration files written for older versions without migrating them, migrate
+rewrites the file itself preserving comments and ordering, and prints a diff of the changes.
+
+Migrate will find the closest '.dots.ya?ml' file the same way as 'dots validate'.
+
+Use the '--config' or '-c' flag in order to pass a path to a dots configuration file.
+Use the '--dry-run' flag to only print the diff without rewriting the file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := resolveConfigPath()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+
+		rewrite, err := config.Migrate(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		if !rewrite.Changed() {
+			fmt.Printf("%s: `%s` is already version `%d`\n", aurora.Blue("Info"), path, config.CurrentVersion)
+			return nil
+		}
+
+		if err = printRewrite(rewrite, "Migrate"); err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		if dryRun {
+			return nil
+		}
+
+		if err = rewrite.Write(); err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: migrated `%s` to version `%d`\n", aurora.Blue("Info"), path, config.CurrentVersion)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to `.dots.yml` file")
+	migrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the diff without rewriting the file")
+}
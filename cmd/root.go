@@ -3,10 +3,17 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/logrusorgru/aurora"
 	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/config"
 )
 
+// Path to a dots configuration file set by the `--config` flag
+var configPath string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "dots",
@@ -24,3 +31,50 @@ func Execute() {
 }
 
 func init() {}
+
+// Resolves the dots configuration file to use, either `--config`, `DOTS_CONFIG` or the closest
+// `.dots.(ya?ml|toml|json)` starting from the current working directory
+func resolveConfigPath() (string, error) {
+	located, err := locateConfig()
+	if err != nil {
+		return "", err
+	}
+	return located.Path, nil
+}
+
+// Locates the dots configuration file to use, see `resolveConfigPath`
+func locateConfig() (*config.LocatedConfig, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	return config.LocateConfig(configPath, cwd)
+}
+
+// Prints a diff of a rewrite followed by each change labeled with label
+func printRewrite(rewrite *config.Rewrite, label string) error {
+	diff, err := rewrite.Diff()
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			fmt.Println(aurora.Bold(line))
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(aurora.Green(line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(aurora.Red(line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Println(aurora.Cyan(line))
+		default:
+			fmt.Println(line)
+		}
+	}
+
+	for _, change := range rewrite.Changes {
+		fmt.Printf("%s: %s\n", aurora.Green(label), change)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/NickHackman/dots/cache"
+)
+
+var jobs int
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade every repository of dotfiles in the cache",
+	Long: `Upgrade every repository of dotfiles in the cache, '$XDG_CACHE_HOME/dots'.
+
+Repositories are upgraded in parallel, use the '--jobs' or '-j' flag to set how many are upgraded
+at once. A repository that fails to upgrade doesn't stop the others, once every repository has been
+upgraded a summary of each is printed along with the commits each upgrade brought in.
+
+Repositories checked out at a tag or commit stay at it, while the lockfile is updated with the new
+commit of every repository that was upgraded.
+
+Fetching a repository fails after 'cache.network_timeout' in the user config, rather than waiting
+forever on an unreachable host. With '--offline', or 'cache.offline' in the user config, nothing is
+upgraded and repositories fetched longer ago than 'cache.ttl' are reported instead.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if jobs < 1 {
+			return fmt.Errorf("`--jobs` must be at least 1, but was %d", jobs)
+		}
+
+		dotsCache := defaultCache()
+		if dotsCache.Offline {
+			reportOffline(dotsCache)
+			os.Exit(1)
+		}
+		results, err := dotsCache.UpgradeAll(jobs)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		if len(results) == 0 {
+			fmt.Printf("%s: there are no repositories in the cache, download one with `dots get`\n", aurora.Blue("Info"))
+			return nil
+		}
+
+		updateLockfile(dotsCache, func(lock *cache.Lockfile) { lock.Update(dotsCache, results) })
+
+		failed := 0
+		for _, result := range results {
+			name, err := filepath.Rel(dotsCache.Dir, result.Path)
+			if err != nil {
+				name = result.Path
+			}
+			name = filepath.ToSlash(name)
+
+			switch {
+			case result.Err != nil:
+				failed++
+				fmt.Printf("%s: %v\n", aurora.Red("Error"), result.Err)
+			case result.Upgraded():
+				fmt.Printf("%s: upgraded `%s` %s -> %s\n", aurora.Green("Upgrade"), name, shortCommit(result.Before), shortCommit(result.After))
+				for _, change := range result.Changes {
+					fmt.Printf("    %s\n", change)
+				}
+			default:
+				fmt.Printf("%s: `%s` is up to date\n", aurora.Blue("Info"), name)
+			}
+		}
+
+		fmt.Printf("\n%d upgraded, %d up to date, %d failed\n", upgraded(results), len(results)-upgraded(results)-failed, failed)
+		if failed != 0 {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+// Reports that repositories can't be upgraded while offline, along with every repository older than the TTL
+func reportOffline(dotsCache *cache.Cache) {
+	fmt.Printf("%s: can't upgrade while offline\n", aurora.Red("Error"))
+	repos, err := dotsCache.Repos()
+	if err != nil {
+		fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+		return
+	}
+	for _, path := range repos {
+		info, err := dotsCache.Info(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			continue
+		}
+		warnExpired(dotsCache, info.Name, info.Fetched)
+	}
+}
+
+// Number of results that were upgraded to a different commit
+func upgraded(results []cache.UpgradeResult) int {
+	count := 0
+	for i := range results {
+		if results[i].Upgraded() {
+			count++
+		}
+	}
+	return count
+}
+
+// Abbreviates a commit the same way as git, `3f2a1c9`
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().BoolVar(&offline, "offline", false, "Never access the network, report repositories older than the cache ttl instead")
+	upgradeCmd.Flags().IntVarP(&jobs, "jobs", "j", 4, "Number of repositories to upgrade at once")
+	upgradeCmd.Flags().StringVar(&lockfilePath, "lockfile", "", "Path to the lockfile (default \"$XDG_CONFIG_HOME/dots/dots.lock\")")
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"text/tabwriter"
 
 	"github.com/logrusorgru/aurora"
 	"github.com/spf13/cobra"
@@ -10,7 +12,11 @@ import (
 	"github.com/NickHackman/dots/config"
 )
 
-var configPath string
+var (
+	fix       bool
+	write     bool
+	listRules bool
+)
 
 // validateCmd represents the validate command
 var validateCmd = &cobra.Command{
@@ -19,37 +25,70 @@ var validateCmd = &cobra.Command{
 	Long: `Validate your .dots.ya?ml configuration file for common issues.
 
 Validate will find the closest '.dots.ya?ml' file By starting at the current working directory
-and progressing upwards until it finds a configuration file or the mount point ('/' on unix systems).
+and progressing upwards until it finds a configuration file, the root of the repository or the
+mount point ('/' on unix systems), run 'dots config path' to see which file is used.
+
+Use the '--config' or '-c' flag or the 'DOTS_CONFIG' environment variable in order to pass a path
+to a dots configuration file.
+
+Use the '--fix' flag to show a diff of the fixes for issues that have a single obvious fix,
+comments and ordering are preserved. The fixes are only written when '--write' is also passed.
+
+Every check is a rule with an ID and a severity, either 'error', 'warning' or 'off'. Use the
+'--list-rules' flag to list every rule. The severity of a rule can be set in the user config at
+'$XDG_CONFIG_HOME/dots/config.yml'
 
-Use the '--config' or '-c' flag in order to pass a path to a dots configuration file.`,
+  rules:
+    description-blank: error
+    duplicate-description: off
+
+and a rule can be ignored by a '# dots:ignore rule-id' comment in the dots configuration file,
+inside of a dotfile's entry it only ignores the rule for that dotfile.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if configPath == "" {
-			path, err := os.Getwd()
-			if err != nil {
-				fmt.Printf("%s: failed to get current working directory - %v", aurora.Red("Error"), err)
-				return err
-			}
+		if write && !fix {
+			return errors.New("`--write` can only be used together with `--fix`")
+		}
+
+		userConf, err := config.LoadUserConfig()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
+		if listRules {
+			printRules(userConf)
+			return nil
+		}
+
+		path, err := resolveConfigPath()
+		if err != nil {
+			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+			os.Exit(1)
+		}
 
-			if configPath, err = config.FindConfig(path); err != nil {
+		if fix {
+			if err = fixConfig(path); err != nil {
 				fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
 				os.Exit(1)
 			}
 		}
 
-		err := config.Validate(configPath)
-		if err == nil {
+		validErr := config.ValidateWith(path, userConf)
+		if validErr == nil {
 			return nil
 		}
 
-		for _, warn := range err.Warnings {
-			fmt.Printf("%s: %s\n", aurora.Yellow("Warning"), warn.Message)
+		for _, warn := range validErr.Warnings {
+			fmt.Printf("%s%s: %s\n", aurora.Yellow("Warning"), ruleLabel(warn.Rule), warn.Message)
 			if warn.Recommendation != "" {
 				fmt.Printf("%s: %s\n", aurora.Blue("Info"), warn.Recommendation)
 			}
 		}
 
-		if err.IsErr() {
-			fmt.Printf("%s: %v\n", aurora.Red("Error"), err)
+		if validErr.IsErr() {
+			fmt.Printf("%s%s: %v\n", aurora.Red("Error"), ruleLabel(validErr.Rule), validErr)
+			if rule := config.LookupRule(validErr.Rule); rule != nil {
+				fmt.Printf("%s: %s\n", aurora.Blue("Info"), rule.Hint)
+			}
 		}
 
 		os.Exit(1)
@@ -58,8 +97,60 @@ Use the '--config' or '-c' flag in order to pass a path to a dots configuration
 	},
 }
 
+// Fixes the dots config at path, printing a diff of the fixes and only writing them if `--write` is set
+func fixConfig(path string) error {
+	rewrite, err := config.Fix(path)
+	if err != nil {
+		return err
+	}
+	if !rewrite.Changed() {
+		fmt.Printf("%s: nothing to fix\n", aurora.Blue("Info"))
+		return nil
+	}
+
+	if err = printRewrite(rewrite, "Fix"); err != nil {
+		return err
+	}
+
+	if !write {
+		fmt.Printf("%s: run with `--write` to apply %d fix(es) to `%s`\n", aurora.Blue("Info"), len(rewrite.Changes), path)
+		return nil
+	}
+	if err = rewrite.Write(); err != nil {
+		return err
+	}
+	fmt.Printf("%s: applied %d fix(es) to `%s`\n", aurora.Blue("Info"), len(rewrite.Changes), path)
+	return nil
+}
+
+// Labels output with the ID of the rule that reported it, such as ` [description-blank]`
+func ruleLabel(id string) string {
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", id)
+}
+
+// Prints every rule with its severity, set in the user config or its default
+func printRules(userConf *config.UserConfig) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tSEVERITY\tDESCRIPTION")
+	for _, rule := range config.Rules() {
+		severity, ok := userConf.Rules[rule.ID]
+		label := severity.String()
+		if !ok {
+			label = fmt.Sprintf("%s (default)", rule.Severity)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", rule.ID, label, rule.Description)
+	}
+	writer.Flush()
+}
+
 func init() {
 	rootCmd.AddCommand(validateCmd)
 
 	validateCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to `.dots.yml` file")
+	validateCmd.Flags().BoolVar(&fix, "fix", false, "Show a diff of fixes for issues that have a single obvious fix")
+	validateCmd.Flags().BoolVar(&write, "write", false, "Write the fixes shown by `--fix`")
+	validateCmd.Flags().BoolVar(&listRules, "list-rules", false, "List every rule and its severity")
 }
@@ -5,7 +5,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -13,58 +12,40 @@ import (
 
 // DotsConfig a Dots config
 type DotsConfig struct {
-	Name     string    `yaml:"name"`     // Name that recognizes a set of dotfiles generally YourNameOrUsername/dotfiles
-	License  string    `yaml:"license"`  // License used for dotfiles
-	URL      string    `yaml:"URL"`      // URL to upstream
-	Dotfiles []Dotfile `yaml:"dotfiles"` // Dotfiles themselves
+	Version      int       `yaml:"version" toml:"version" json:"version"`                                                 // Version of the dots config format, see CurrentVersion
+	Name         string    `yaml:"name,omitempty" toml:"name,omitempty" json:"name,omitempty"`                            // Name that recognizes a set of dotfiles generally YourNameOrUsername/dotfiles
+	License      string    `yaml:"license" toml:"license" json:"license"`                                                 // License used for dotfiles
+	URL          string    `yaml:"URL,omitempty" toml:"URL,omitempty" json:"URL,omitempty"`                               // URL to upstream
+	Include      []string  `yaml:"include,omitempty" toml:"include,omitempty" json:"include,omitempty"`                   // Paths or glob patterns of other files whose dotfiles are merged into Dotfiles
+	Defaults     *Defaults `yaml:"defaults,omitempty" toml:"defaults,omitempty" json:"defaults,omitempty"`                // Fallback values for every Dotfile, including Dotfiles in included files
+	AllowSecrets []string  `yaml:"allow_secrets,omitempty" toml:"allow_secrets,omitempty" json:"allow_secrets,omitempty"` // Paths relative to the project root, optionally suffixed by `:line`, whose possible secrets are false positives
+	Dotfiles     []Dotfile `yaml:"dotfiles,omitempty" toml:"dotfiles,omitempty" json:"dotfiles,omitempty"`                // Dotfiles themselves
 }
 
 // Dotfile a specific dotfile
 type Dotfile struct {
-	Name            string `yaml:"name"`             // Name that will be used to identify this specific Dotfile
-	Description     string `yaml:"description"`      // Describe this specific dotfile or collection of dotfiles
-	Source          string `yaml:"source"`           // Path to this dotfile
-	Destination     string `yaml:"destination"`      // Path to install to
-	InstallChildren bool   `yaml:"install_children"` // If true dictates that this dotfile is a logical organization of multiple dotfiles
+	Name            string `yaml:"name" toml:"name" json:"name"`                                                                   // Name that will be used to identify this specific Dotfile
+	Description     string `yaml:"description,omitempty" toml:"description,omitempty" json:"description,omitempty"`                // Describe this specific dotfile or collection of dotfiles
+	Source          string `yaml:"source,omitempty" toml:"source,omitempty" json:"source,omitempty"`                               // Path to this dotfile
+	Destination     string `yaml:"destination,omitempty" toml:"destination,omitempty" json:"destination,omitempty"`                // Path to install to
+	InstallChildren bool   `yaml:"install_children,omitempty" toml:"install_children,omitempty" json:"install_children,omitempty"` // If true dictates that this dotfile is a logical organization of multiple dotfiles
 }
 
-const (
-	configRegexp = `\.dots\.ya?ml`
-)
-
-// MountPointError is an error dictating that when searching for a `.dots.ya?ml` file
-// one could not be found until it reached the mount point
-type MountPointError struct {
-	StartPoint string // Directory where search started at
-	EndPoint   string // Highest directory found that ended discovery, generally `/` (on Unix)
+// SourceOutsideRootError is an error dictating that a Dotfile's source, or a symbolic link inside
+// of it, resolves to a path outside of the project root
+type SourceOutsideRootError struct {
+	Name     string // Name of the Dotfile
+	Path     string // Path that escapes the project root, either the source or a symbolic link inside of it
+	Resolved string // Path after evaluating symbolic links
+	Root     string // Project root, the directory containing `.dots.ya?ml`
 }
 
-// Error returns a String stating that the start p
-func (mpe *MountPointError) Error() string {
-	return fmt.Sprintf("failed to find `.dots.ya?ml` starting from: `%s` reached mount point `%s`", mpe.StartPoint, mpe.EndPoint)
-}
-
-// FindConfig finds .dots.ya?ml going upward till root is reached
-//
-// Root is determined if calling filepath.Dir(current) results in the same
-// path as seen in this example https://golang.org/pkg/path/filepath/#Dir
-func FindConfig(startDir string) (string, error) {
-	previous, current := "", startDir
-	configRegex := regexp.MustCompile(configRegexp)
-
-	for previous != current {
-		files, err := ioutil.ReadDir(current)
-		if err != nil {
-			return "", fmt.Errorf("failed to read directory `%s`: %w", current, err)
-		}
-		for _, file := range files {
-			if configRegex.MatchString(file.Name()) {
-				return filepath.Join(current, file.Name()), nil
-			}
-		}
-		previous, current = current, filepath.Dir(current)
+// Error returns a String stating which path escapes the project root
+func (sore *SourceOutsideRootError) Error() string {
+	if sore.Path == sore.Resolved {
+		return fmt.Sprintf("dotfile `%s` source `%s` is outside of the project root `%s`", sore.Name, sore.Path, sore.Root)
 	}
-	return "", &MountPointError{StartPoint: startDir, EndPoint: current}
+	return fmt.Sprintf("dotfile `%s` source `%s` resolves to `%s` which is outside of the project root `%s`", sore.Name, sore.Path, sore.Resolved, sore.Root)
 }
 
 // Parse a `.dots.ya?ml`, starting from `start` progress upwards towards mount point.
@@ -84,18 +65,18 @@ func Parse(start string) (*DotsConfig, error) {
 	return ParseFile(configPath)
 }
 
-// Expands environment variables and '~' in Destination
+// Expands environment variables, placeholders and '~' in Destination
 //
-// If Dotfile.Destination isn't set, set it to its default value `~/.config/$name`
+// If Dotfile.Destination isn't set, set it to its default value `<config>/$name`
 func (dot *Dotfile) expandDestination() error {
 	if dot.Destination == "" {
-		configDir, err := os.UserConfigDir()
-		if err != nil {
-			return fmt.Errorf("failed to get User config directory: %w", err)
-		}
-		dot.Destination = filepath.Join(configDir, dot.Name)
+		dot.Destination = fmt.Sprintf("<config>%c%s", os.PathSeparator, dot.Name)
 	}
-	dot.Destination = os.ExpandEnv(dot.Destination)
+	destination, err := dot.expandEnv("destination", dot.Destination)
+	if err != nil {
+		return err
+	}
+	dot.Destination = destination
 
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -107,36 +88,189 @@ func (dot *Dotfile) expandDestination() error {
 	} else if strings.HasPrefix(dot.Destination, "~/") {
 		dot.Destination = filepath.Join(home, dot.Destination[2:])
 	}
-	return nil
+	dot.Destination, err = dot.expandPlaceholder("destination", dot.Destination, "")
+	return err
 }
 
-// Expands environment variables and <root> in Source
+// Expands environment variables and placeholders in Source
+//
+// If Dotfile.Source isn't set, set it to its default value `<root>/$name`.
+// Relative sources are relative to projectRoot.
 //
-// If Dotfile.Source isn't set, set it to its default value `<root>/$name`
-func (dot *Dotfile) expandSource(projectRoot string) {
+// Source CANNOT be outside of projectRoot, this is checked after cleaning `..`
+// and evaluating symbolic links, including any symbolic links inside of Source.
+func (dot *Dotfile) expandSource(projectRoot string) error {
 	if dot.Source == "" {
 		dot.Source = fmt.Sprintf("<root>%c%s", os.PathSeparator, dot.Name)
 	}
-	dot.Source = os.ExpandEnv(dot.Source)
-	if strings.HasPrefix(dot.Source, "<root>") {
-		dot.Source = filepath.Join(projectRoot, dot.Source[6:])
+	source, err := dot.expandEnv("source", dot.Source)
+	if err != nil {
+		return err
+	}
+	if source, err = dot.expandPlaceholder("source", source, projectRoot); err != nil {
+		return err
+	}
+	if dot.Source = source; !filepath.IsAbs(dot.Source) {
+		dot.Source = filepath.Join(projectRoot, dot.Source)
+	}
+	dot.Source = filepath.Clean(dot.Source)
+	return dot.checkSourceWithinRoot(projectRoot)
+}
+
+// Checks that Source and every symbolic link inside of Source resolve to a path inside of projectRoot
+func (dot *Dotfile) checkSourceWithinRoot(projectRoot string) error {
+	root, err := resolvePath(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to resolve project root `%s`: %w", projectRoot, err)
+	}
+
+	resolved, err := resolvePath(dot.Source)
+	if err != nil {
+		return fmt.Errorf("dotfile `%s` failed to resolve source `%s`: %w", dot.Name, dot.Source, err)
+	}
+	if !isWithin(root, resolved) {
+		return &SourceOutsideRootError{Name: dot.Name, Path: dot.Source, Resolved: resolved, Root: projectRoot}
+	}
+
+	if _, err := os.Lstat(resolved); os.IsNotExist(err) {
+		return nil
 	}
+
+	return dot.checkLinksWithinRoot(root, projectRoot, resolved, make(map[string]bool))
 }
 
-// ParseFile parses a 'dots.(yml|yaml)' file
+// Checks that every symbolic link inside of dir, a resolved path, resolves to a path inside of root,
+// projectRoot resolved. Links to directories are followed so the links inside of them are checked as
+// well, visited holds the directories already checked so a cycle of links ends.
+func (dot *Dotfile) checkLinksWithinRoot(root, projectRoot, dir string, visited map[string]bool) error {
+	if visited[dir] {
+		return nil
+	}
+	visited[dir] = true
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("dotfile `%s` failed to read `%s`: %w", dot.Name, path, err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return fmt.Errorf("dotfile `%s` failed to read symbolic link `%s`: %w", dot.Name, path, err)
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		if target, err = resolvePath(target); err != nil {
+			return fmt.Errorf("dotfile `%s` failed to resolve symbolic link `%s`: %w", dot.Name, path, err)
+		}
+		if !isWithin(root, target) {
+			return &SourceOutsideRootError{Name: dot.Name, Path: path, Resolved: target, Root: projectRoot}
+		}
+		// filepath.Walk doesn't follow links, so the links inside of a linked directory are checked separately
+		if stat, err := os.Stat(target); err == nil && stat.IsDir() {
+			return dot.checkLinksWithinRoot(root, projectRoot, target, visited)
+		}
+		return nil
+	})
+}
+
+// Resolves all symbolic links in path, unlike `filepath.EvalSymlinks` path doesn't have to exist.
+// The longest existing prefix of path is evaluated and the remainder is joined onto it.
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	existing, rest := path, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path, nil
+		}
+		existing, rest = parent, filepath.Join(filepath.Base(existing), rest)
+	}
+}
+
+// Checks if path is root or inside of root, both are expected to be absolute and clean
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) && !filepath.IsAbs(rel)
+}
+
+// InstallPaths the paths that this Dotfile installs to once expanded
+//
+// Generally this is only Destination, but if InstallChildren is set it's every child of
+// Source joined onto Destination.
+func (dot *Dotfile) InstallPaths() ([]string, error) {
+	if !dot.InstallChildren {
+		return []string{filepath.Clean(dot.Destination)}, nil
+	}
+
+	children, err := ioutil.ReadDir(dot.Source)
+	if err != nil {
+		return nil, fmt.Errorf("dotfile `%s` failed to read directory `%s`: %w", dot.Name, dot.Source, err)
+	}
+	paths := make([]string, len(children))
+	for i, child := range children {
+		paths[i] = filepath.Join(dot.Destination, child.Name())
+	}
+	return paths, nil
+}
+
+// ParseFile parses a 'dots.(yml|yaml|toml|json)' file, the format is detected from its extension
+//
+// Dots configs written for an older version of dots are migrated to CurrentVersion in memory.
+//
+// Dotfiles from files listed in `include` and `*.(ya?ml|toml|json)` files in `.dots.d/` next to the file are
+// merged into Dotfiles, `<root>` in these files is the directory containing the file at path.
 func ParseFile(path string) (*DotsConfig, error) {
-	bytes, err := ioutil.ReadFile(path)
+	bytes, _, err := readFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file `%s`: %w", path, err)
+		return nil, err
+	}
+	if bytes, _, err = migrate(bytes); err != nil {
+		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
 	}
 	dotsConf := DotsConfig{}
 	if err := yaml.Unmarshal(bytes, &dotsConf); err != nil {
 		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
 	}
+	if dotsConf.Defaults != nil {
+		if dotsConf.Dotfiles, err = dotsConf.Defaults.decodeDotfiles(bytes); err != nil {
+			return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+		}
+	}
+	if err = checkAllowSecrets(dotsConf.AllowSecrets); err != nil {
+		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+	}
 
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
 	projectRoot := filepath.Dir(path)
+	if err = dotsConf.mergeFragments(path, projectRoot); err != nil {
+		return nil, err
+	}
+
 	for i := range dotsConf.Dotfiles {
-		dotsConf.Dotfiles[i].expandSource(projectRoot)
+		if err := dotsConf.Dotfiles[i].expandSource(projectRoot); err != nil {
+			return nil, err
+		}
 		if err := dotsConf.Dotfiles[i].expandDestination(); err != nil {
 			return nil, err
 		}
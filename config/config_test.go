@@ -3,6 +3,7 @@ package config_test
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -24,6 +25,36 @@ func pathToTestData() (string, error) {
 	return filepath.FromSlash(testDataSlash), nil
 }
 
+// Copies the directory name in testdata to a temporary directory, so tests can write files inside of it
+// without changing testdata, the caller removes it
+func copyTestData(t *testing.T, name string) string {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup testing: %w", err)
+	dir, err := ioutil.TempDir("", "dots-"+name)
+	assert.NoErrorf(t, err, "failed to setup testing can't create temporary directory: %w", err)
+
+	source := filepath.Join(testData, name)
+	err = filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dir, rel), info.Mode().Perm())
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, rel), contents, info.Mode().Perm())
+	})
+	assert.NoErrorf(t, err, "failed to setup testing can't copy `%s`: %w", source, err)
+	return dir
+}
+
 func TestValidParse(t *testing.T) {
 	testData, err := pathToTestData()
 	assert.NoErrorf(t, err, "failed to setup config_test.go testing: %w", err)
@@ -41,8 +72,9 @@ func TestValidParse(t *testing.T) {
 		{
 			path: "template.yml",
 			expected: &config.DotsConfig{
+				Version: config.CurrentVersion,
 				Name:    "YourName/dotfiles",
-				License: "GPLv3",
+				License: "GPL-3.0-only",
 				URL:     "https://github.com/NickHackman/dots",
 				Dotfiles: []config.Dotfile{
 					{
@@ -64,24 +96,27 @@ func TestValidParse(t *testing.T) {
 		{
 			path: "no-dotfiles.yml",
 			expected: &config.DotsConfig{
+				Version: config.CurrentVersion,
 				Name:    "YourName/dotfiles",
-				License: "GPLv3",
+				License: "GPL-3.0-only",
 				URL:     "https://github.com/NickHackman/dots",
 			},
 		},
 		{
 			path: "empty-dotfiles.yml",
 			expected: &config.DotsConfig{
+				Version: config.CurrentVersion,
 				Name:    "YourName/dotfiles",
-				License: "GPLv3",
+				License: "GPL-3.0-only",
 				URL:     "https://github.com/NickHackman/dots",
 			},
 		},
 		{
 			path: "many-dotfiles.yml",
 			expected: &config.DotsConfig{
+				Version: config.CurrentVersion,
 				Name:    "YourName/dotfiles",
-				License: "GPLv3",
+				License: "GPL-3.0-only",
 				URL:     "https://github.com/NickHackman/dots",
 				Dotfiles: []config.Dotfile{
 					{
@@ -125,10 +160,17 @@ func TestParseCWD(t *testing.T) {
 	assert.NoErrorf(t, err, "failed to setup config_test.go testing couldn't get absolute path of `.`: %w", err)
 	current, previous := abs, ""
 	for current != previous {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			break
+		}
 		current, previous = filepath.Dir(current), current
 	}
 	_, err = config.Parse(abs)
-	assert.EqualError(t, err, fmt.Sprintf("failed to find `.dots.ya?ml` starting from: `%s` reached mount point `%s`", abs, current))
+	if current == previous {
+		assert.EqualError(t, err, fmt.Sprintf("failed to find `.dots.(ya?ml|toml|json)` starting from: `%s` reached mount point `%s`", abs, current))
+	} else {
+		assert.EqualError(t, err, fmt.Sprintf("failed to find `.dots.(ya?ml|toml|json)` starting from: `%s` reached repository root `%s`", abs, current))
+	}
 }
 
 func TestParseValid(t *testing.T) {
@@ -140,3 +182,88 @@ func TestParseValid(t *testing.T) {
 	_, err = config.Parse(abs)
 	assert.NoError(t, err)
 }
+
+func TestParseSourceSymlinkOutsideRoot(t *testing.T) {
+	outside, err := ioutil.TempDir("", "dots-outside")
+	assert.NoErrorf(t, err, "failed to setup config_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(outside)
+
+	root, err := ioutil.TempDir("", "dots-root")
+	assert.NoErrorf(t, err, "failed to setup config_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(root)
+
+	conf := "name: YourName/dotfiles\nlicense: GPL-3.0-only\ndotfiles:\n  - name: escape\n"
+	configPath := filepath.Join(root, ".dots.yml")
+	assert.NoError(t, ioutil.WriteFile(configPath, []byte(conf), 0644))
+
+	resolvedOutside, err := filepath.EvalSymlinks(outside)
+	assert.NoError(t, err)
+
+	t.Run("source", func(t *testing.T) {
+		source := filepath.Join(root, "escape")
+		assert.NoError(t, os.Symlink(outside, source))
+		defer os.Remove(source)
+
+		_, err := config.ParseFile(configPath)
+		var sourceErr *config.SourceOutsideRootError
+		if assert.True(t, errors.As(err, &sourceErr)) {
+			assert.Equal(t, source, sourceErr.Path)
+			assert.Equal(t, resolvedOutside, sourceErr.Resolved)
+		}
+	})
+
+	t.Run("child", func(t *testing.T) {
+		source := filepath.Join(root, "escape")
+		assert.NoError(t, os.Mkdir(source, 0755))
+		defer os.RemoveAll(source)
+		child := filepath.Join(source, ".zshrc")
+		assert.NoError(t, os.Symlink(filepath.Join(outside, ".zshrc"), child))
+
+		_, err := config.ParseFile(configPath)
+		var sourceErr *config.SourceOutsideRootError
+		if assert.True(t, errors.As(err, &sourceErr)) {
+			assert.Equal(t, child, sourceErr.Path)
+			assert.Equal(t, filepath.Join(resolvedOutside, ".zshrc"), sourceErr.Resolved)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		// A link to a directory inside of the root that itself links outside of the root
+		other := filepath.Join(root, "other")
+		assert.NoError(t, os.Mkdir(other, 0755))
+		defer os.RemoveAll(other)
+		assert.NoError(t, os.Symlink(outside, filepath.Join(other, "evil")))
+		source := filepath.Join(root, "escape")
+		assert.NoError(t, os.Symlink(other, source))
+		defer os.Remove(source)
+
+		resolvedRoot, err := filepath.EvalSymlinks(root)
+		assert.NoError(t, err)
+		_, err = config.ParseFile(configPath)
+		var sourceErr *config.SourceOutsideRootError
+		if assert.True(t, errors.As(err, &sourceErr)) {
+			assert.Equal(t, filepath.Join(resolvedRoot, "other", "evil"), sourceErr.Path)
+			assert.Equal(t, resolvedOutside, sourceErr.Resolved)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		source := filepath.Join(root, "escape")
+		assert.NoError(t, os.MkdirAll(filepath.Join(source, "nested"), 0755))
+		defer os.RemoveAll(source)
+		assert.NoError(t, os.Symlink(source, filepath.Join(source, "nested", "parent")))
+
+		_, err := config.ParseFile(configPath)
+		assert.NoError(t, err)
+	})
+
+	t.Run("inside", func(t *testing.T) {
+		source := filepath.Join(root, "escape")
+		assert.NoError(t, os.Mkdir(source, 0755))
+		defer os.RemoveAll(source)
+		assert.NoError(t, os.Symlink(configPath, filepath.Join(source, "link")))
+
+		_, err := config.ParseFile(configPath)
+		assert.NoError(t, err)
+	})
+}
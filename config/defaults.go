@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults fallback values for every Dotfile that doesn't set the field itself
+type Defaults struct {
+	SourceRoot      string `yaml:"source_root,omitempty" toml:"source_root,omitempty" json:"source_root,omitempty"`                // Directory sources are in by default, a Dotfile without a source uses `$source_root/$name`
+	DestinationRoot string `yaml:"destination_root,omitempty" toml:"destination_root,omitempty" json:"destination_root,omitempty"` // Directory destinations are in by default, a Dotfile without a destination uses `$destination_root/$name`
+	InstallChildren bool   `yaml:"install_children,omitempty" toml:"install_children,omitempty" json:"install_children,omitempty"` // Default of install_children, a Dotfile can set `install_children: false` to override it
+}
+
+// Dotfiles of a file, decoded as nodes so each can be decoded on top of the defaults
+type dotfileNodes struct {
+	Dotfiles []yaml.Node `yaml:"dotfiles"`
+}
+
+// Decodes the dotfiles in contents, a dots config or fragment as YAML, starting each from the defaults
+// so only fields the dotfile sets itself override them
+//
+// The roots are applied before sources and destinations are expanded, so they can use
+// environment variables and placeholders the same as a source or destination.
+func (defaults *Defaults) decodeDotfiles(contents []byte) ([]Dotfile, error) {
+	var nodes dotfileNodes
+	if err := yaml.Unmarshal(contents, &nodes); err != nil {
+		return nil, err
+	}
+
+	var dotfiles []Dotfile
+	for i := range nodes.Dotfiles {
+		dot := Dotfile{InstallChildren: defaults.InstallChildren}
+		if err := nodes.Dotfiles[i].Decode(&dot); err != nil {
+			return nil, err
+		}
+		if dot.Source == "" && defaults.SourceRoot != "" {
+			dot.Source = joinRoot(defaults.SourceRoot, dot.Name)
+		}
+		if dot.Destination == "" && defaults.DestinationRoot != "" {
+			dot.Destination = joinRoot(defaults.DestinationRoot, dot.Name)
+		}
+		dotfiles = append(dotfiles, dot)
+	}
+	return dotfiles, nil
+}
+
+// Joins a root from Defaults and the name of a Dotfile, the same way as the default source `<root>/$name`
+func joinRoot(root, name string) string {
+	return fmt.Sprintf("%s%c%s", strings.TrimRight(root, `/\`), os.PathSeparator, name)
+}
@@ -0,0 +1,74 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseDefaults(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup defaults_test.go testing: %w", err)
+
+	homeDir, err := os.UserHomeDir()
+	assert.NoErrorf(t, err, "failed to setup defaults_test.go testing can't locate `$HOME`: %w", err)
+
+	configDir, err := config.ResolvePlaceholder("config")
+	assert.NoError(t, err)
+	dataDir, err := config.ResolvePlaceholder("data")
+	assert.NoError(t, err)
+
+	root := filepath.Join(testData, "defaults")
+	expected := []config.Dotfile{
+		{
+			Name:            "zsh",
+			Description:     "Z shell",
+			Source:          filepath.Join(root, "config", "zsh"),
+			Destination:     filepath.Join(dataDir, "zsh"),
+			InstallChildren: true,
+		},
+		{
+			Name:        "bspwm",
+			Description: "Binary Space Partition Window Manager",
+			Source:      filepath.Join(root, "config", "bspwm"),
+			Destination: filepath.Join(configDir, "bspwm"),
+		},
+		{
+			Name:            "home",
+			Description:     "Files installed directly into home",
+			Source:          filepath.Join(root, "config", "home"),
+			Destination:     homeDir,
+			InstallChildren: true,
+		},
+	}
+
+	dotsConfig, err := config.ParseFile(filepath.Join(root, ".dots.yml"))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, dotsConfig.Dotfiles)
+
+	resolved, err := config.Resolve(filepath.Join(root, ".dots.yml"), config.YAML)
+	assert.NoError(t, err)
+	resolvedConfig := config.DotsConfig{}
+	assert.NoError(t, yaml.Unmarshal(resolved, &resolvedConfig))
+	assert.Nil(t, resolvedConfig.Defaults)
+	assert.Equal(t, expected, resolvedConfig.Dotfiles)
+}
+
+func TestConvertDefaults(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup defaults_test.go testing: %w", err)
+
+	converted, err := config.Convert(filepath.Join(testData, "defaults", ".dots.yml"), config.JSON)
+	assert.NoError(t, err)
+	dotsConfig := config.DotsConfig{}
+	assert.NoError(t, yaml.Unmarshal(converted, &dotsConfig))
+	assert.Equal(t, &config.Defaults{SourceRoot: "<root>/config", DestinationRoot: "<data>"}, dotsConfig.Defaults)
+	assert.Equal(t, []config.Dotfile{
+		{Name: "zsh", Description: "Z shell", InstallChildren: true},
+		{Name: "bspwm", Description: "Binary Space Partition Window Manager", Destination: "<config>/bspwm"},
+	}, dotsConfig.Dotfiles)
+}
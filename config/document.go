@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// document a dots config as both its text and its yaml.Node tree
+//
+// Edits are located using the tree, but applied to the text, this way comments,
+// ordering and blank lines are preserved exactly as they were written.
+type document struct {
+	lines []string
+	root  *yaml.Node // Top level mapping, nil if the document is empty
+	edits []textEdit
+}
+
+type editKind int
+
+const (
+	replaceEdit editKind = iota
+	deleteEdit
+	insertEdit
+)
+
+// textEdit a single edit to a line of a document
+type textEdit struct {
+	kind  editKind
+	line  int    // 0-based index of the line edited, for inserts the line is inserted before it
+	start int    // 0-based byte offset the replacement starts at
+	end   int    // 0-based byte offset the replacement ends at
+	text  string // Replacement text or the inserted line
+	order int    // Order the edit was made in
+}
+
+// Parses a YAML document while keeping its text
+func parseDocument(bytes []byte) (*document, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(bytes, &node); err != nil {
+		return nil, err
+	}
+
+	doc := &document{lines: strings.Split(string(bytes), "\n")}
+	if len(node.Content) == 0 {
+		return doc, nil
+	}
+	if node.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("expected a mapping at the top level of the document")
+	}
+	doc.root = node.Content[0]
+	return doc, nil
+}
+
+// Gets the key and value nodes of key in mapping, both are nil if key isn't present
+func mappingValue(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// Checks if a node is null, either `~`, `null` or left empty
+func isNull(node *yaml.Node) bool {
+	return node == nil || (node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null")
+}
+
+// Encodes value as a YAML scalar, quoting it only if it's necessary
+func yamlScalar(value string) string {
+	bytes, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSuffix(string(bytes), "\n")
+}
+
+// Converts a 1-based yaml.Node column, which counts characters, into a byte offset into line
+func columnOffset(line string, column int) int {
+	characters := 0
+	for offset := range line {
+		if characters == column-1 {
+			return offset
+		}
+		characters++
+	}
+	return len(line)
+}
+
+// Finds the span of the value of the key starting at keyOffset in line, excluding any trailing comment
+func valueSpan(line string, keyOffset int) (int, int, bool) {
+	colon := -1
+	var quote byte
+	for i := keyOffset; i < len(line) && colon == -1; i++ {
+		switch {
+		case quote != 0:
+			if line[i] == quote {
+				quote = 0
+			}
+		case line[i] == '"' || line[i] == '\'':
+			quote = line[i]
+		case line[i] == ':' && (i+1 == len(line) || line[i+1] == ' ' || line[i+1] == '\t'):
+			colon = i
+		}
+	}
+	if colon == -1 {
+		return 0, 0, false
+	}
+
+	start, end := colon+1, len(line)
+	quote = 0
+	for i := start; i < len(line); i++ {
+		switch {
+		case quote != 0:
+			if line[i] == quote {
+				quote = 0
+			}
+		case line[i] == '"' || line[i] == '\'':
+			quote = line[i]
+		case line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t'):
+			end = i
+			i = len(line)
+		}
+	}
+	return start, start + len(strings.TrimRight(line[start:end], " \t")), true
+}
+
+// Sets the value of key to text, the value MUST be on the same line as the key
+func (doc *document) setValue(key, value *yaml.Node, text string) bool {
+	if key == nil || (value != nil && value.Line != key.Line && !isNull(value)) {
+		return false
+	}
+
+	line := doc.lines[key.Line-1]
+	start, end, ok := valueSpan(line, columnOffset(line, key.Column))
+	if !ok {
+		return false
+	}
+	doc.edits = append(doc.edits, textEdit{kind: replaceEdit, line: key.Line - 1, start: start, end: end, text: " " + text, order: len(doc.edits)})
+	return true
+}
+
+// Inserts `key: text` before the 1-based line, indented to column
+func (doc *document) insertKey(line, column int, key, text string) {
+	doc.insertLine(line, fmt.Sprintf("%s%s: %s", strings.Repeat(" ", column-1), key, text))
+}
+
+// Inserts text as a new line before the 1-based line
+func (doc *document) insertLine(line int, text string) {
+	doc.edits = append(doc.edits, textEdit{kind: insertEdit, line: line - 1, text: text, order: len(doc.edits)})
+}
+
+// Deletes key and its value, only when both are alone on a single line
+func (doc *document) deleteKey(key, value *yaml.Node) bool {
+	if key == nil || value == nil || value.Line != key.Line || value.Kind != yaml.ScalarNode {
+		return false
+	}
+
+	line := doc.lines[key.Line-1]
+	if strings.TrimSpace(line[:columnOffset(line, key.Column)]) != "" {
+		return false
+	}
+	doc.edits = append(doc.edits, textEdit{kind: deleteEdit, line: key.Line - 1, order: len(doc.edits)})
+	return true
+}
+
+// Applies every edit made to the document returning the edited text
+func (doc *document) bytes() []byte {
+	edits := append([]textEdit{}, doc.edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line > edits[j].line
+		}
+		if edits[i].kind != edits[j].kind {
+			return edits[i].kind < edits[j].kind
+		}
+		if edits[i].kind == replaceEdit {
+			return edits[i].start > edits[j].start
+		}
+		return edits[i].order > edits[j].order
+	})
+
+	lines := append([]string{}, doc.lines...)
+	for _, edit := range edits {
+		switch edit.kind {
+		case replaceEdit:
+			line := lines[edit.line]
+			lines[edit.line] = line[:edit.start] + edit.text + line[edit.end:]
+		case deleteEdit:
+			lines = append(lines[:edit.line], lines[edit.line+1:]...)
+		case insertEdit:
+			lines = append(lines[:edit.line], append([]string{edit.text}, lines[edit.line:]...)...)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvError is an error dictating that a Dotfile's source or destination couldn't be expanded,
+// generally since it uses an environment variable that's unset or empty
+type EnvError struct {
+	Name     string // Name of the Dotfile
+	Field    string // Field being expanded, either `source` or `destination`
+	Value    string // Value of the field
+	Variable string // Environment variable, empty if the syntax itself is invalid
+	Reason   string // Reason the expansion failed
+}
+
+// Error returns a String stating which field couldn't be expanded and why
+func (ee *EnvError) Error() string {
+	if ee.Variable == "" {
+		return fmt.Sprintf("dotfile `%s` %s `%s` %s", ee.Name, ee.Field, ee.Value, ee.Reason)
+	}
+	return fmt.Sprintf("dotfile `%s` %s `%s` environment variable `%s` %s", ee.Name, ee.Field, ee.Value, ee.Variable, ee.Reason)
+}
+
+// Expands environment variables in value strictly, unlike `os.ExpandEnv` an unset or empty variable is an
+// `EnvError` rather than silently expanding to nothing, which could turn `$UNSET/foo` into `/foo`
+//
+// $VAR, ${VAR}         - the value of VAR, an error if VAR is unset or empty
+// ${VAR:-default}      - the value of VAR, or default if VAR is unset or empty
+// ${VAR:?message}      - the value of VAR, an error stating message if VAR is unset or empty
+// $$                   - a literal `$`
+func (dot *Dotfile) expandEnv(field, value string) (string, error) {
+	envErr := &EnvError{Name: dot.Name, Field: field, Value: value}
+
+	var expanded strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' {
+			expanded.WriteByte(value[i])
+			continue
+		}
+		if i+1 < len(value) && value[i+1] == '$' {
+			expanded.WriteByte('$')
+			i++
+			continue
+		}
+
+		if i+1 < len(value) && value[i+1] == '{' {
+			end := strings.IndexByte(value[i:], '}')
+			if end == -1 {
+				envErr.Reason = "has a `${` without a closing `}`"
+				return "", envErr
+			}
+			result, err := expandBraces(envErr, value[i+2:i+end])
+			if err != nil {
+				return "", err
+			}
+			expanded.WriteString(result)
+			i += end
+			continue
+		}
+
+		name := envName(value[i+1:])
+		if name == "" {
+			envErr.Reason = "has a `$` that isn't followed by an environment variable, write `$$` for a literal `$`"
+			return "", envErr
+		}
+		envValue, err := lookupEnv(envErr, name)
+		if err != nil {
+			return "", err
+		}
+		expanded.WriteString(envValue)
+		i += len(name)
+	}
+	return expanded.String(), nil
+}
+
+// Expands the contents of `${...}`, a default is used as is without being expanded
+func expandBraces(envErr *EnvError, contents string) (string, error) {
+	name := envName(contents)
+	if name == "" {
+		envErr.Reason = fmt.Sprintf("has `${%s}` which doesn't start with the name of an environment variable", contents)
+		return "", envErr
+	}
+
+	operator := contents[len(name):]
+	switch {
+	case operator == "":
+		return lookupEnv(envErr, name)
+	case strings.HasPrefix(operator, ":-"):
+		if envValue := os.Getenv(name); envValue != "" {
+			return envValue, nil
+		}
+		return operator[2:], nil
+	case strings.HasPrefix(operator, ":?"):
+		if envValue := os.Getenv(name); envValue != "" {
+			return envValue, nil
+		}
+		envErr.Variable = name
+		envErr.Reason = "is unset or empty"
+		if message := operator[2:]; message != "" {
+			envErr.Reason = fmt.Sprintf("is unset or empty: %s", message)
+		}
+		return "", envErr
+	}
+	envErr.Reason = fmt.Sprintf("has `${%s}` which isn't one of `${VAR}`, `${VAR:-default}` or `${VAR:?message}`", contents)
+	return "", envErr
+}
+
+// Looks up an environment variable which MUST be set and not empty
+func lookupEnv(envErr *EnvError, name string) (string, error) {
+	envValue, ok := os.LookupEnv(name)
+	if !ok {
+		envErr.Variable = name
+		envErr.Reason = "is undefined"
+		return "", envErr
+	}
+	if envValue == "" {
+		envErr.Variable = name
+		envErr.Reason = "is empty"
+		return "", envErr
+	}
+	return envValue, nil
+}
+
+// The name of the environment variable that value starts with, empty if it doesn't start with one
+func envName(value string) string {
+	for i, c := range value {
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isLetter && (i == 0 || c < '0' || c > '9') {
+			return value[:i]
+		}
+	}
+	return value
+}
@@ -0,0 +1,96 @@
+package config_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-expand")
+	assert.NoErrorf(t, err, "failed to setup expand_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	defer os.Unsetenv("DOTS_TEST_SET")
+	defer os.Unsetenv("DOTS_TEST_EMPTY")
+	assert.NoError(t, os.Setenv("DOTS_TEST_SET", "/set"))
+	assert.NoError(t, os.Setenv("DOTS_TEST_EMPTY", ""))
+	assert.NoError(t, os.Unsetenv("DOTS_TEST_UNSET"))
+
+	tests := []struct {
+		destination string
+		expected    string
+		err         error
+	}{
+		{destination: "$DOTS_TEST_SET/nvim", expected: "/set/nvim"},
+		{destination: "${DOTS_TEST_SET}nvim", expected: "/setnvim"},
+		{destination: "${DOTS_TEST_UNSET:-/default}/nvim", expected: "/default/nvim"},
+		{destination: "${DOTS_TEST_EMPTY:-/default}/nvim", expected: "/default/nvim"},
+		{destination: "${DOTS_TEST_SET:?must be set}/nvim", expected: "/set/nvim"},
+		{destination: "/price/$$5", expected: "/price/$5"},
+		{
+			destination: "$DOTS_TEST_UNSET/nvim",
+			err:         &config.EnvError{Name: "nvim", Field: "destination", Value: "$DOTS_TEST_UNSET/nvim", Variable: "DOTS_TEST_UNSET", Reason: "is undefined"},
+		},
+		{
+			destination: "${DOTS_TEST_EMPTY}/nvim",
+			err:         &config.EnvError{Name: "nvim", Field: "destination", Value: "${DOTS_TEST_EMPTY}/nvim", Variable: "DOTS_TEST_EMPTY", Reason: "is empty"},
+		},
+		{
+			destination: "${DOTS_TEST_UNSET:?set it to your fonts directory}/nvim",
+			err: &config.EnvError{
+				Name:     "nvim",
+				Field:    "destination",
+				Value:    "${DOTS_TEST_UNSET:?set it to your fonts directory}/nvim",
+				Variable: "DOTS_TEST_UNSET",
+				Reason:   "is unset or empty: set it to your fonts directory",
+			},
+		},
+		{
+			destination: "/price/$5",
+			err:         &config.EnvError{Name: "nvim", Field: "destination", Value: "/price/$5", Reason: "has a `$` that isn't followed by an environment variable, write `$$` for a literal `$`"},
+		},
+		{
+			destination: "${DOTS_TEST_SET/nvim",
+			err:         &config.EnvError{Name: "nvim", Field: "destination", Value: "${DOTS_TEST_SET/nvim", Reason: "has a `${` without a closing `}`"},
+		},
+		{
+			destination: "${DOTS_TEST_SET:=/default}",
+			err:         &config.EnvError{Name: "nvim", Field: "destination", Value: "${DOTS_TEST_SET:=/default}", Reason: "has `${DOTS_TEST_SET:=/default}` which isn't one of `${VAR}`, `${VAR:-default}` or `${VAR:?message}`"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.destination, func(t *testing.T) {
+			path := filepath.Join(dir, ".dots.yml")
+			contents := fmt.Sprintf("license: MIT\ndotfiles:\n  - name: nvim\n    destination: '%s'\n", test.destination)
+			assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+			dotsConfig, err := config.ParseFile(path)
+			if test.err != nil {
+				assert.Equal(t, test.err, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, filepath.FromSlash(test.expected), dotsConfig.Dotfiles[0].Destination)
+		})
+	}
+}
+
+func TestExpandEnvSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-expand")
+	assert.NoErrorf(t, err, "failed to setup expand_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.Unsetenv("DOTS_TEST_UNSET"))
+	path := filepath.Join(dir, ".dots.yml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("license: MIT\ndotfiles:\n  - name: nvim\n    source: <root>/$DOTS_TEST_UNSET\n"), 0644))
+
+	_, err = config.ParseFile(path)
+	assert.EqualError(t, err, "dotfile `nvim` source `<root>/$DOTS_TEST_UNSET` environment variable `DOTS_TEST_UNSET` is undefined")
+}
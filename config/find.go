@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	configRegexp = `^\.dots\.(ya?ml|toml|json)$`
+
+	// ConfigEnv environment variable that sets the path to the dots config, overriding discovery
+	ConfigEnv = "DOTS_CONFIG"
+)
+
+// Matches the name of a dots config
+var configRegex = regexp.MustCompile(configRegexp)
+
+// Files and directories that mark the root of a repository, discovery doesn't go above it
+var vcsMarkers = []string{".git", ".hg", ".svn", ".bzr"}
+
+// MountPointError is an error dictating that when searching for a `.dots.(ya?ml|toml|json)` file
+// one could not be found until it reached the mount point
+type MountPointError struct {
+	StartPoint string // Directory where search started at
+	EndPoint   string // Highest directory found that ended discovery, generally `/` (on Unix)
+}
+
+// Error returns a String stating that the start p
+func (mpe *MountPointError) Error() string {
+	return fmt.Sprintf("failed to find `.dots.(ya?ml|toml|json)` starting from: `%s` reached mount point `%s`", mpe.StartPoint, mpe.EndPoint)
+}
+
+// RepositoryRootError is an error dictating that when searching for a `.dots.(ya?ml|toml|json)` file
+// one could not be found until it reached the root of the enclosing repository
+type RepositoryRootError struct {
+	StartPoint string // Directory where search started at
+	Root       string // Root of the repository that ended discovery, the directory containing `.git`
+}
+
+// Error returns a String stating where the search started and the repository root it stopped at
+func (rre *RepositoryRootError) Error() string {
+	return fmt.Sprintf("failed to find `.dots.(ya?ml|toml|json)` starting from: `%s` reached repository root `%s`", rre.StartPoint, rre.Root)
+}
+
+// AmbiguousConfigError is an error dictating that a directory contains both `.dots.yml` and `.dots.yaml`
+type AmbiguousConfigError struct {
+	Paths []string // Paths of the dots configs
+}
+
+// Error returns a String stating which dots configs conflict
+func (ace *AmbiguousConfigError) Error() string {
+	return fmt.Sprintf("both `%s` and `%s` exist, remove one of them", ace.Paths[0], ace.Paths[1])
+}
+
+// ConfigOrigin how a dots config was located
+type ConfigOrigin int
+
+const (
+	// OriginFlag passed with `--config`
+	OriginFlag ConfigOrigin = iota
+	// OriginEnv set by the environment variable `DOTS_CONFIG`
+	OriginEnv
+	// OriginSearch found searching upwards from the current directory
+	OriginSearch
+)
+
+// LocatedConfig a dots config and why it was chosen
+type LocatedConfig struct {
+	Path    string       // Path to the dots config
+	Origin  ConfigOrigin // How the dots config was located
+	Start   string       // Directory the search started at, only set for OriginSearch
+	Ignored []string     // Dots configs in the same directory ignored since their format has a lower precedence
+}
+
+// Reason describes why the dots config was chosen
+func (located *LocatedConfig) Reason() string {
+	switch located.Origin {
+	case OriginFlag:
+		return "passed with `--config`"
+	case OriginEnv:
+		return fmt.Sprintf("set by the environment variable `%s`", ConfigEnv)
+	}
+	if located.Start == filepath.Dir(located.Path) {
+		return fmt.Sprintf("found in `%s`", located.Start)
+	}
+	return fmt.Sprintf("closest dots config searching upwards from `%s`", located.Start)
+}
+
+// FindConfig finds .dots.(ya?ml|toml|json) going upward till the root of the enclosing
+// repository or the mount point is reached
+//
+// When a directory contains multiple dots configs the one in the format with the
+// highest precedence is used, in order `.yml`, `.yaml`, `.toml` then `.json`, however
+// `.dots.yml` and `.dots.yaml` together are an `AmbiguousConfigError`.
+//
+// The repository root is the first directory containing `.git`, `.hg`, `.svn` or `.bzr`, when
+// there isn't one the mount point is determined if calling filepath.Dir(current) results in the
+// same path as seen in this example https://golang.org/pkg/path/filepath/#Dir
+func FindConfig(startDir string) (string, error) {
+	located, err := findConfig(startDir)
+	if err != nil {
+		return "", err
+	}
+	return located.Path, nil
+}
+
+// LocateConfig locates the dots config to use, in order of priority
+//
+// 1. flagPath, if it isn't blank
+// 2. The environment variable `DOTS_CONFIG`, if it isn't blank
+// 3. The closest dots config starting from startDir, see `FindConfig`
+func LocateConfig(flagPath, startDir string) (*LocatedConfig, error) {
+	if flagPath != "" {
+		return locateFile(flagPath, OriginFlag)
+	}
+	if envPath := os.Getenv(ConfigEnv); envPath != "" {
+		return locateFile(envPath, OriginEnv)
+	}
+	return findConfig(startDir)
+}
+
+// Locates a dots config passed explicitly, it MUST exist
+func locateFile(path string, origin ConfigOrigin) (*LocatedConfig, error) {
+	located := &LocatedConfig{Path: path, Origin: origin}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("dots config `%s` %s: %w", path, located.Reason(), err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("dots config `%s` %s is a directory", path, located.Reason())
+	}
+	return located, nil
+}
+
+// Searches upwards from startDir for the closest dots config, see `FindConfig`
+func findConfig(startDir string) (*LocatedConfig, error) {
+	previous, current := "", startDir
+
+	for previous != current {
+		matches, isRoot, err := configsIn(current)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(matches) != 0 {
+			return locateIn(current, startDir, matches)
+		}
+		if isRoot {
+			return nil, &RepositoryRootError{StartPoint: startDir, Root: current}
+		}
+		previous, current = current, filepath.Dir(current)
+	}
+	return nil, &MountPointError{StartPoint: startDir, EndPoint: current}
+}
+
+// FindConfigIn finds .dots.(ya?ml|toml|json) in root itself, without searching upwards, for when root
+// is known to be the root of a repository
+//
+// The same precedence as `FindConfig` is used, when there isn't a dots config in root it's a
+// `RepositoryRootError`.
+func FindConfigIn(root string) (string, error) {
+	matches, _, err := configsIn(root)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", &RepositoryRootError{StartPoint: root, Root: root}
+	}
+	located, err := locateIn(root, root, matches)
+	if err != nil {
+		return "", err
+	}
+	return located.Path, nil
+}
+
+// Names of the dots configs in dir and whether dir is the root of a repository
+func configsIn(dir string) ([]string, bool, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read directory `%s`: %w", dir, err)
+	}
+
+	var matches []string
+	isRoot := false
+	for _, file := range files {
+		if configRegex.MatchString(file.Name()) && !file.IsDir() {
+			matches = append(matches, file.Name())
+		}
+		for _, marker := range vcsMarkers {
+			isRoot = isRoot || file.Name() == marker
+		}
+	}
+	return matches, isRoot, nil
+}
+
+// Chooses between the dots configs found in dir by the precedence of their format
+func locateIn(dir, startDir string, matches []string) (*LocatedConfig, error) {
+	best := matches[0]
+	for _, match := range matches[1:] {
+		if formatPrecedence(match) < formatPrecedence(best) {
+			best = match
+		}
+	}
+
+	located := &LocatedConfig{Path: filepath.Join(dir, best), Origin: OriginSearch, Start: startDir}
+	for _, match := range matches {
+		if match == best {
+			continue
+		}
+		// YAML always has the highest precedence, so best is YAML as well
+		if format, _ := FormatOf(match); format == YAML {
+			return nil, &AmbiguousConfigError{Paths: []string{located.Path, filepath.Join(dir, match)}}
+		}
+		located.Ignored = append(located.Ignored, filepath.Join(dir, match))
+	}
+	return located, nil
+}
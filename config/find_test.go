@@ -0,0 +1,118 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindConfigAnchored(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-find")
+	assert.NoErrorf(t, err, "failed to setup find_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	child := filepath.Join(dir, "child")
+	assert.NoError(t, os.Mkdir(child, 0755))
+	for _, name := range []string{"x.dots.yml.bak", "x.dots.yml", ".dots.yml.orig"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(child, name), []byte{}, 0644))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".dots.yml"), []byte{}, 0644))
+
+	path, err := config.FindConfig(child)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ".dots.yml"), path)
+}
+
+func TestFindConfigRepositoryRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-find")
+	assert.NoErrorf(t, err, "failed to setup find_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	repo := filepath.Join(dir, "repo")
+	start := filepath.Join(repo, "nvim")
+	assert.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+	assert.NoError(t, os.Mkdir(start, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".dots.yml"), []byte{}, 0644))
+
+	_, err = config.FindConfig(start)
+	assert.Equal(t, &config.RepositoryRootError{StartPoint: start, Root: repo}, err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(repo, ".dots.toml"), []byte{}, 0644))
+	path, err := config.FindConfig(start)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo, ".dots.toml"), path)
+}
+
+func TestFindConfigIn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-find")
+	assert.NoErrorf(t, err, "failed to setup find_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	// Not a repository root, but the parent's dots config still isn't found
+	root := filepath.Join(dir, "repo")
+	assert.NoError(t, os.Mkdir(root, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".dots.yml"), []byte{}, 0644))
+	_, err = config.FindConfigIn(root)
+	assert.IsType(t, &config.RepositoryRootError{}, err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, ".dots.json"), []byte{}, 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, ".dots.toml"), []byte{}, 0644))
+	path, err := config.FindConfigIn(root)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, ".dots.toml"), path)
+}
+
+func TestFindConfigAmbiguous(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-find")
+	assert.NoErrorf(t, err, "failed to setup find_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{".dots.yml", ".dots.yaml", ".dots.json"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644))
+	}
+
+	_, err = config.FindConfig(dir)
+	assert.Equal(t, &config.AmbiguousConfigError{Paths: []string{filepath.Join(dir, ".dots.yml"), filepath.Join(dir, ".dots.yaml")}}, err)
+}
+
+func TestLocateConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-find")
+	assert.NoErrorf(t, err, "failed to setup find_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	found := filepath.Join(dir, ".dots.yml")
+	env := filepath.Join(dir, "env.yml")
+	flag := filepath.Join(dir, "flag.yml")
+	for _, path := range []string{found, env, flag, filepath.Join(dir, ".dots.json")} {
+		assert.NoError(t, ioutil.WriteFile(path, []byte{}, 0644))
+	}
+
+	defer os.Unsetenv(config.ConfigEnv)
+	assert.NoError(t, os.Unsetenv(config.ConfigEnv))
+
+	located, err := config.LocateConfig("", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &config.LocatedConfig{
+		Path:    found,
+		Origin:  config.OriginSearch,
+		Start:   dir,
+		Ignored: []string{filepath.Join(dir, ".dots.json")},
+	}, located)
+
+	assert.NoError(t, os.Setenv(config.ConfigEnv, env))
+	located, err = config.LocateConfig("", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &config.LocatedConfig{Path: env, Origin: config.OriginEnv}, located)
+
+	located, err = config.LocateConfig(flag, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &config.LocatedConfig{Path: flag, Origin: config.OriginFlag}, located)
+
+	assert.NoError(t, os.Setenv(config.ConfigEnv, filepath.Join(dir, "missing.yml")))
+	_, err = config.LocateConfig("", dir)
+	assert.Error(t, err)
+}
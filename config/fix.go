@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultName = "YourName/dotfiles"
+)
+
+// Fix fixes issues in the dots config at path that have a single obvious fix, for the most part these are
+// the recommendations of the `Warning`s produced by `Validate`
+//
+// Name            - set to `YourName/dotfiles` if blank
+// License         - replaced with its canonical SPDX expression
+// Description     - set to a placeholder if blank
+// Destination     - `~` is quoted, unquoted it's null and would install to the default destination
+// Source          - removed if it's the default `<root>/$name`
+//
+// Fixes are located using the yaml.Node tree of the config, but applied to its text,
+// so comments, ordering and blank lines are preserved.
+func Fix(path string) (*Rewrite, error) {
+	bytes, err := readRewritable(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDocument(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+	}
+
+	rewrite := &Rewrite{Path: path, Original: bytes}
+	if doc.root != nil && len(doc.root.Content) != 0 {
+		rewrite.Changes = append(rewrite.Changes, doc.fixName()...)
+		rewrite.Changes = append(rewrite.Changes, doc.fixLicense()...)
+		rewrite.Changes = append(rewrite.Changes, doc.fixDots()...)
+	}
+	rewrite.Rewritten = doc.bytes()
+	return rewrite, nil
+}
+
+func (doc *document) fixName() []string {
+	key, value := mappingValue(doc.root, "name")
+	if value != nil && strings.TrimSpace(value.Value) != "" && !isNull(value) {
+		return nil
+	}
+
+	fix := fmt.Sprintf("set name to `%s`", defaultName)
+	if key == nil {
+		first := doc.root.Content[0]
+		doc.insertKey(first.Line, first.Column, "name", defaultName)
+		return []string{fix}
+	}
+	if doc.setValue(key, value, defaultName) {
+		return []string{fix}
+	}
+	return nil
+}
+
+func (doc *document) fixLicense() []string {
+	key, value := mappingValue(doc.root, "license")
+	if isNull(value) {
+		return nil
+	}
+
+	expression, err := ParseLicense(value.Value)
+	if err != nil || expression.IsCanonical() {
+		return nil
+	}
+	if doc.setValue(key, value, yamlScalar(expression.Canonical)) {
+		return []string{fmt.Sprintf("set license `%s` to `%s`", expression.Expression, expression.Canonical)}
+	}
+	return nil
+}
+
+func (doc *document) fixDots() []string {
+	_, dots := mappingValue(doc.root, "dotfiles")
+	if dots == nil {
+		return nil
+	}
+
+	var fixes []string
+	for _, dot := range dots.Content {
+		nameKey, nameValue := mappingValue(dot, "name")
+		if isNull(nameValue) || nameValue.Value == "" {
+			continue
+		}
+		name := nameValue.Value
+
+		descriptionKey, descriptionValue := mappingValue(dot, "description")
+		placeholder := yamlScalar(fmt.Sprintf("TODO: describe %s", name))
+		if descriptionKey == nil && nameKey.Line == nameValue.Line {
+			doc.insertKey(nameKey.Line+1, nameKey.Column, "description", placeholder)
+			fixes = append(fixes, fmt.Sprintf("added a placeholder description to dotfile `%s`", name))
+		} else if isNull(descriptionValue) || strings.TrimSpace(descriptionValue.Value) == "" {
+			if doc.setValue(descriptionKey, descriptionValue, placeholder) {
+				fixes = append(fixes, fmt.Sprintf("added a placeholder description to dotfile `%s`", name))
+			}
+		}
+
+		destinationKey, destinationValue := mappingValue(dot, "destination")
+		if destinationKey != nil && isNull(destinationValue) && destinationValue.Value == "~" {
+			if doc.setValue(destinationKey, destinationValue, `"~"`) {
+				fixes = append(fixes, fmt.Sprintf("quoted destination `~` of dotfile `%s`, unquoted it's null", name))
+			}
+		}
+
+		sourceKey, sourceValue := mappingValue(dot, "source")
+		if sourceValue != nil && strings.TrimSuffix(sourceValue.Value, "/") == "<root>/"+name {
+			if doc.deleteKey(sourceKey, sourceValue) {
+				fixes = append(fixes, fmt.Sprintf("removed source of dotfile `%s` which is the default `<root>/%s`", name, name))
+			}
+		}
+	}
+	return fixes
+}
@@ -0,0 +1,65 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFix(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup fix_test.go testing: %w", err)
+
+	before := filepath.Join(testData, "fix", "before.yml")
+	expected, err := ioutil.ReadFile(filepath.Join(testData, "fix", "after.yml"))
+	assert.NoErrorf(t, err, "failed to setup fix_test.go testing can't read `after.yml`: %w", err)
+
+	result, err := config.Fix(before)
+	assert.NoError(t, err)
+	assert.True(t, result.Changed())
+	assert.Equal(t, string(expected), string(result.Rewritten))
+	assert.Equal(t, []string{
+		"set name to `YourName/dotfiles`",
+		"set license `GPLv3` to `GPL-3.0-only`",
+		"added a placeholder description to dotfile `bspwm`",
+		"removed source of dotfile `bspwm` which is the default `<root>/bspwm`",
+		"added a placeholder description to dotfile `keybinds`",
+		"quoted destination `~` of dotfile `keybinds`, unquoted it's null",
+	}, result.Changes)
+
+	diff, err := result.Diff()
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "\n-license: GPLv3 # the same as LICENSE\n")
+	assert.Contains(t, diff, "\n+license: GPL-3.0-only # the same as LICENSE\n")
+}
+
+func TestFixNothingToFix(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup fix_test.go testing: %w", err)
+
+	result, err := config.Fix(filepath.Join(testData, "install-children-shared-destination.yml"))
+	assert.NoError(t, err)
+	assert.False(t, result.Changed())
+	assert.Equal(t, result.Original, result.Rewritten)
+}
+
+func TestFixWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-fix")
+	assert.NoErrorf(t, err, "failed to setup fix_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".dots.yml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("license: MIT\ndotfiles:\n  - name: bspwm\n"), 0644))
+
+	result, err := config.Fix(path)
+	assert.NoError(t, err)
+	assert.NoError(t, result.Write())
+
+	bytes, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "name: YourName/dotfiles\nlicense: MIT\ndotfiles:\n  - name: bspwm\n    description: 'TODO: describe bspwm'\n", string(bytes))
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format a file format that dots configs can be written in
+type Format string
+
+const (
+	// YAML the default format `.dots.yml` or `.dots.yaml`
+	YAML Format = "yaml"
+	// TOML `.dots.toml`
+	TOML Format = "toml"
+	// JSON `.dots.json`
+	JSON Format = "json"
+)
+
+// Every supported extension in order of precedence, when a directory contains
+// multiple dots configs the one with the extension that comes first is used
+var formatExtensions = []struct {
+	extension string
+	format    Format
+}{
+	{".yml", YAML},
+	{".yaml", YAML},
+	{".toml", TOML},
+	{".json", JSON},
+}
+
+// FormatError is an error dictating that a file isn't in a supported Format
+type FormatError struct {
+	Name string // Name of the format or path of the file
+}
+
+// Error returns a String stating the supported formats
+func (fe *FormatError) Error() string {
+	return fmt.Sprintf("`%s` isn't a supported format, expected one of `yaml`, `toml` or `json`", fe.Name)
+}
+
+// ParseFormat parses the name of a Format, such as `toml`, extensions like `yml` are also accepted
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimPrefix(name, "."))
+	for _, ext := range formatExtensions {
+		if name == ext.extension[1:] {
+			return ext.format, nil
+		}
+	}
+	return "", &FormatError{Name: name}
+}
+
+// Extension the preferred extension of a Format including the leading `.`
+func (f Format) Extension() string {
+	for _, ext := range formatExtensions {
+		if ext.format == f {
+			return ext.extension
+		}
+	}
+	return ""
+}
+
+// FormatOf detects the Format of a file from its extension
+func FormatOf(path string) (Format, error) {
+	format, err := ParseFormat(filepath.Ext(path))
+	if err != nil {
+		return "", &FormatError{Name: path}
+	}
+	return format, nil
+}
+
+// Precedence of a dots config's name, lower is preferred, -1 if it isn't a dots config
+func formatPrecedence(name string) int {
+	for i, ext := range formatExtensions {
+		if strings.HasSuffix(name, ext.extension) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Reads a file in any Format, returning its contents as YAML and the Format it was written in
+//
+// YAML is returned as is, TOML and JSON are decoded and encoded as YAML so every format
+// goes through the same migrations and decoding.
+func readFile(path string) ([]byte, Format, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, "", err
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file `%s`: %w", path, err)
+	}
+
+	var generic map[string]interface{}
+	switch format {
+	case YAML:
+		return contents, format, nil
+	case TOML:
+		if _, err = toml.Decode(string(contents), &generic); err != nil {
+			return nil, "", fmt.Errorf("failed to parse `%s`: %w", path, err)
+		}
+	case JSON:
+		if err = json.Unmarshal(contents, &generic); err != nil {
+			return nil, "", fmt.Errorf("failed to parse `%s`: %w", path, err)
+		}
+	}
+
+	if len(generic) == 0 {
+		return []byte{}, format, nil
+	}
+	contents, err = yaml.Marshal(generic)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to convert `%s` to yaml: %w", path, err)
+	}
+	return contents, format, nil
+}
+
+// Reads a dots config that's going to be rewritten, only YAML can be rewritten since it preserves comments
+func readRewritable(path string) ([]byte, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	if format != YAML {
+		return nil, fmt.Errorf("`%s` is %s, only YAML dots configs can be rewritten, convert it with `dots convert --to yaml`", path, strings.ToUpper(string(format)))
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file `%s`: %w", path, err)
+	}
+	return contents, nil
+}
+
+// Convert converts the dots config at path to format, the dots config is migrated to CurrentVersion
+// and written as it is, placeholders and environment variables aren't expanded.
+//
+// Comments aren't preserved. A default `install_children: true` is written onto each dotfile instead,
+// since a dotfile overriding it with `install_children: false` would otherwise be lost.
+func Convert(path string, format Format) ([]byte, error) {
+	contents, _, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if contents, _, err = migrate(contents); err != nil {
+		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+	}
+
+	dotsConf := DotsConfig{}
+	if err = yaml.Unmarshal(contents, &dotsConf); err != nil {
+		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+	}
+	if defaults := dotsConf.Defaults; defaults != nil && defaults.InstallChildren {
+		if dotsConf.Dotfiles, err = (&Defaults{InstallChildren: true}).decodeDotfiles(contents); err != nil {
+			return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+		}
+		if defaults.InstallChildren = false; *defaults == (Defaults{}) {
+			dotsConf.Defaults = nil
+		}
+	}
+	return encode(path, &dotsConf, format)
+}
+
+// Resolve parses the dots config at path and encodes it in format the way dots sees it, included
+// files are merged, defaults are applied and sources and destinations are expanded
+//
+// The result has no `include` or `defaults` since they're already applied.
+func Resolve(path string, format Format) ([]byte, error) {
+	dotsConf, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dotsConf.Include, dotsConf.Defaults = nil, nil
+	return encode(path, dotsConf, format)
+}
+
+// Encodes dotsConf, read from path, in format
+func encode(path string, dotsConf *DotsConfig, format Format) ([]byte, error) {
+	var err error
+	var buf bytes.Buffer
+	switch format {
+	case YAML:
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		err = encoder.Encode(dotsConf)
+	case TOML:
+		err = toml.NewEncoder(&buf).Encode(dotsConf)
+	case JSON:
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(dotsConf)
+	default:
+		err = &FormatError{Name: string(format)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert `%s` to %s: %w", path, format, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFormats(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup format_test.go testing: %w", err)
+
+	homeDir, err := os.UserHomeDir()
+	assert.NoErrorf(t, err, "failed to setup format_test.go testing can't locate `$HOME`: %w", err)
+
+	root := filepath.Join(testData, "formats")
+	expected := &config.DotsConfig{
+		Version: config.CurrentVersion,
+		Name:    "YourName/dotfiles",
+		License: "GPL-3.0-only",
+		URL:     "https://github.com/NickHackman/dots",
+		Dotfiles: []config.Dotfile{
+			{
+				Name:            "zsh",
+				Description:     "Z shell",
+				Source:          filepath.Join(root, "zsh"),
+				Destination:     homeDir,
+				InstallChildren: true,
+			},
+		},
+	}
+
+	for _, name := range []string{"dots.yml", "dots.toml", "dots.json"} {
+		t.Run(name, func(t *testing.T) {
+			dotsConfig, err := config.ParseFile(filepath.Join(root, name))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, dotsConfig)
+			assert.Nil(t, config.Validate(filepath.Join(root, name)))
+		})
+	}
+}
+
+func TestValidateFormats(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup format_test.go testing: %w", err)
+
+	expected := &config.ValidationError{
+		Warnings: []*config.Warning{
+			{
+				Message:        "dotfile `zsh` description shouldn't be left blank",
+				Recommendation: "describe the dotfile, `dots validate --fix` adds a placeholder",
+				Rule:           "description-blank",
+			},
+		},
+	}
+	for _, name := range []string{"invalid-blank-description.yml", "invalid-blank-description.toml", "invalid-blank-description.json"} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, config.Validate(filepath.Join(testData, "formats", name)))
+		})
+	}
+}
+
+func TestFindConfigPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-format")
+	assert.NoErrorf(t, err, "failed to setup format_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{".dots.json", ".dots.toml", ".dots.yaml"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644))
+
+		path, err := config.FindConfig(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, name), path)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	root := copyTestData(t, "formats")
+	defer os.RemoveAll(root)
+
+	expected, err := config.ParseFile(filepath.Join(root, "dots.yml"))
+	assert.NoError(t, err)
+
+	for _, format := range []config.Format{config.YAML, config.TOML, config.JSON} {
+		t.Run(string(format), func(t *testing.T) {
+			converted, err := config.Convert(filepath.Join(root, "dots.yml"), format)
+			assert.NoError(t, err)
+
+			// Converted configs are written next to the original so `<root>` stays the same
+			path := filepath.Join(root, "converted"+format.Extension())
+			assert.NoError(t, ioutil.WriteFile(path, converted, 0644))
+
+			dotsConfig, err := config.ParseFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, dotsConfig)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]config.Format{"yaml": config.YAML, "yml": config.YAML, ".toml": config.TOML, "JSON": config.JSON} {
+		format, err := config.ParseFormat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, format)
+	}
+
+	_, err := config.ParseFormat("ini")
+	assert.Equal(t, &config.FormatError{Name: "ini"}, err)
+}
+
+func TestFixOnlyYAML(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup format_test.go testing: %w", err)
+
+	_, err = config.Fix(filepath.Join(testData, "formats", "dots.toml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Matches `# dots:ignore rule-id` comments, multiple rules are separated by `,` or spaces
+var ignoreRegexp = regexp.MustCompile(`#[ \t]*dots:ignore[ \t]+([\w-]+(?:[ \t,]+[\w-]+)*)`)
+
+// ignores Rules suppressed by `# dots:ignore rule-id` comments in a dots config
+//
+// A comment inside of a dotfile's entry in `dotfiles` only ignores the rule for that dotfile,
+// any other comment ignores the rule for the whole dots config.
+type ignores struct {
+	file     map[string]bool         // Rules ignored for the whole dots config
+	dotfiles map[int]map[string]bool // Rules ignored by the index of the dotfile
+	unknown  []string                // IDs that aren't a Rule
+}
+
+// Checks if the dotfile at index or the whole dots config ignores ruleID
+func (ig *ignores) dotfile(ruleID string, index int) bool {
+	return ig.file[ruleID] || ig.dotfiles[index][ruleID]
+}
+
+// Reads every `# dots:ignore` comment in the dots config at path
+//
+// Comments are only read from the dots config itself, not included files. YAML supports ignoring
+// rules for a single dotfile, in TOML every comment applies to the whole dots config and JSON has no comments.
+func readIgnores(path string) (*ignores, error) {
+	ig := &ignores{file: make(map[string]bool), dotfiles: make(map[int]map[string]bool)}
+	format, err := FormatOf(path)
+	if err != nil || format == JSON {
+		return ig, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file `%s`: %w", path, err)
+	}
+	if format == TOML {
+		ig.add(ig.file, string(contents))
+		return ig, nil
+	}
+
+	var node yaml.Node
+	if err = yaml.Unmarshal(contents, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+	}
+	ig.add(ig.file, comments(&node, false))
+	if len(node.Content) == 0 {
+		return ig, nil
+	}
+
+	root := node.Content[0]
+	ig.add(ig.file, comments(root, false))
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		ig.add(ig.file, comments(key, true))
+		if key.Value != "dotfiles" || value.Kind != yaml.SequenceNode {
+			ig.add(ig.file, comments(value, true))
+			continue
+		}
+
+		ig.add(ig.file, comments(value, false))
+		for index, dot := range value.Content {
+			ig.dotfiles[index] = make(map[string]bool)
+			ig.add(ig.dotfiles[index], comments(dot, true))
+		}
+	}
+	return ig, nil
+}
+
+// Adds every rule ignored by `# dots:ignore` comments in text to ignored
+func (ig *ignores) add(ignored map[string]bool, text string) {
+	for _, match := range ignoreRegexp.FindAllStringSubmatch(text, -1) {
+		for _, id := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if LookupRule(id) == nil {
+				ig.unknown = append(ig.unknown, id)
+				continue
+			}
+			ignored[id] = true
+		}
+	}
+}
+
+// Every comment attached to node, and if recursive to its descendants as well
+func comments(node *yaml.Node, recursive bool) string {
+	text := strings.Join([]string{node.HeadComment, node.LineComment, node.FootComment}, "\n")
+	if !recursive {
+		return text
+	}
+	for _, child := range node.Content {
+		text += "\n" + comments(child, true)
+	}
+	return text
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	fragmentsDir = ".dots.d"
+)
+
+// fragment a YAML file included by a dots config, fragments can only contain dotfiles
+type fragment struct {
+	Dotfiles []Dotfile `yaml:"dotfiles"`
+}
+
+// DuplicateDotfileError is an error dictating that two files merged into the same dots config define the same dotfile
+type DuplicateDotfileError struct {
+	Name   string // Name of the dotfile
+	First  string // Path to the file that defined the dotfile first
+	Second string // Path to the file that defined the dotfile again
+}
+
+// Error returns a String stating which files both define the dotfile
+func (dde *DuplicateDotfileError) Error() string {
+	return fmt.Sprintf("dotfile `%s` is defined in both `%s` and `%s`", dde.Name, dde.First, dde.Second)
+}
+
+// Finds the fragments of the dots config at path, first every file matched by `include` in the
+// order they're listed and then every `*.(ya?ml|toml|json)` file in `.dots.d/` next to the dots config in lexical order
+//
+// Fragments CANNOT be outside of projectRoot, the same as a Dotfile's source.
+func findFragments(path, projectRoot string, include []string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(projectRoot, filepath.FromSlash(pattern))
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	var fragments []string
+	seen := map[string]bool{path: true}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include `%s` is an invalid pattern: %w", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return nil, fmt.Errorf("include `%s` does not exist", pattern)
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				fragments = append(fragments, match)
+			}
+		}
+	}
+
+	dirFragments, err := filepath.Glob(filepath.Join(projectRoot, fragmentsDir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirFragments)
+	for _, match := range dirFragments {
+		if _, err := FormatOf(match); err == nil && !seen[match] {
+			seen[match] = true
+			fragments = append(fragments, match)
+		}
+	}
+
+	root, err := resolvePath(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project root `%s`: %w", projectRoot, err)
+	}
+	for _, fragment := range fragments {
+		resolved, err := resolvePath(fragment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve include `%s`: %w", fragment, err)
+		}
+		if !isWithin(root, resolved) {
+			return nil, fmt.Errorf("include `%s` is outside of the project root `%s`", fragment, projectRoot)
+		}
+	}
+	return fragments, nil
+}
+
+// Includes returns the `include` patterns of the dots config at path, relative to its directory, without
+// reading the fragments they match
+func Includes(path string) ([]string, error) {
+	contents, _, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var partial struct {
+		Include []string `yaml:"include"`
+	}
+	if err = yaml.Unmarshal(contents, &partial); err != nil {
+		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+	}
+	return partial.Include, nil
+}
+
+// Parses a fragment, only the key `dotfiles` is allowed, defaults can be nil
+func parseFragment(path string, defaults *Defaults) ([]Dotfile, error) {
+	contents, _, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var frag fragment
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&frag); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse `%s`, included files can only contain `dotfiles`: %w", path, err)
+	}
+	if defaults == nil {
+		return frag.Dotfiles, nil
+	}
+	dotfiles, err := defaults.decodeDotfiles(contents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse `%s`: %w", path, err)
+	}
+	return dotfiles, nil
+}
+
+// Merges every fragment of the dots config at path into dotsConf, reporting dotfiles
+// defined by more than one file
+func (dotsConf *DotsConfig) mergeFragments(path, projectRoot string) error {
+	fragments, err := findFragments(path, projectRoot, dotsConf.Include)
+	if err != nil {
+		return err
+	}
+
+	definedIn := make(map[string]string)
+	for _, dot := range dotsConf.Dotfiles {
+		definedIn[dot.Name] = path
+	}
+
+	for _, fragment := range fragments {
+		dots, err := parseFragment(fragment, dotsConf.Defaults)
+		if err != nil {
+			return err
+		}
+		for _, dot := range dots {
+			if first, ok := definedIn[dot.Name]; ok && first != fragment && dot.Name != "" {
+				return &DuplicateDotfileError{Name: dot.Name, First: first, Second: fragment}
+			}
+			definedIn[dot.Name] = fragment
+		}
+		dotsConf.Dotfiles = append(dotsConf.Dotfiles, dots...)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInclude(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup include_test.go testing: %w", err)
+
+	homeDir, err := os.UserHomeDir()
+	assert.NoErrorf(t, err, "failed to setup include_test.go testing can't locate `$HOME`: %w", err)
+
+	configDir, err := os.UserConfigDir()
+	assert.NoErrorf(t, err, "failed to setup include_test.go testing can't locate `$XDG_CONFIG_HOME`: %w", err)
+
+	root := filepath.Join(testData, "include")
+	path := filepath.Join(root, ".dots.yml")
+	dotsConfig, err := config.ParseFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []config.Dotfile{
+		{
+			Name:        "zsh",
+			Description: "Z shell",
+			Source:      filepath.Join(root, "zsh"),
+			Destination: filepath.Join(configDir, "zsh"),
+		},
+		{
+			Name:        "bspwm",
+			Description: "A simple configuration file for the Binary Space Partition Window Manager",
+			Source:      filepath.Join(root, "bspwm"),
+			Destination: filepath.Join(configDir, "bspwm"),
+		},
+		{
+			Name:            "keybinds",
+			Description:     "Keybindings that escape <-> capslock and handle function keys",
+			Source:          filepath.Join(root, "keybinds"),
+			Destination:     homeDir,
+			InstallChildren: true,
+		},
+	}, dotsConfig.Dotfiles)
+
+	assert.Nil(t, config.Validate(path))
+
+	includes, err := config.Includes(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"extra/*.yml"}, includes)
+}
+
+func TestParseIncludeDuplicate(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup include_test.go testing: %w", err)
+
+	root := filepath.Join(testData, "include-duplicate")
+	_, err = config.ParseFile(filepath.Join(root, ".dots.yml"))
+	assert.Equal(t, &config.DuplicateDotfileError{
+		Name:   "zsh",
+		First:  filepath.Join(root, ".dots.yml"),
+		Second: filepath.Join(root, ".dots.d", "zsh.yml"),
+	}, err)
+	assert.EqualError(t, err, "dotfile `zsh` is defined in both `"+filepath.Join(root, ".dots.yml")+"` and `"+filepath.Join(root, ".dots.d", "zsh.yml")+"`")
+}
+
+func TestParseIncludeInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-include")
+	assert.NoErrorf(t, err, "failed to setup include_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	assert.NoError(t, os.Mkdir(root, 0755))
+	path := filepath.Join(root, ".dots.yml")
+	outside := filepath.Join(dir, "outside.yml")
+	assert.NoError(t, ioutil.WriteFile(outside, []byte("dotfiles:\n  - name: outside\n"), 0644))
+
+	tests := []struct {
+		name     string
+		config   string
+		fragment string
+		err      string
+	}{
+		{
+			name:   "missing",
+			config: "include:\n  - missing.yml\n",
+			err:    "include `" + filepath.Join(root, "missing.yml") + "` does not exist",
+		},
+		{
+			name:   "outside-root",
+			config: "include:\n  - ../outside.yml\n",
+			err:    "include `" + outside + "` is outside of the project root `" + root + "`",
+		},
+		{
+			name:     "unknown-key",
+			config:   "include:\n  - fragment.yml\n",
+			fragment: "name: YourName/dotfiles\n",
+			err:      "failed to parse `" + filepath.Join(root, "fragment.yml") + "`, included files can only contain `dotfiles`: yaml: unmarshal errors:\n  line 1: field name not found in type config.fragment",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.NoError(t, ioutil.WriteFile(path, []byte(test.config), 0644))
+			if test.fragment != "" {
+				assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "fragment.yml"), []byte(test.fragment), 0644))
+			}
+
+			_, err := config.ParseFile(path)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// knownApp an application whose configuration directory is recognized by `Infer`
+type knownApp struct {
+	description string
+	destination string // Destination for the directory, if all of its children are dot-prefixed `~` is used instead
+}
+
+// Applications recognized by the name of their directory
+var knownApps = map[string]knownApp{
+	"alacritty": {"Alacritty, a GPU accelerated terminal emulator", "<config>/alacritty"},
+	"bash":      {"Bourne Again shell", "<config>/bash"},
+	"bspwm":     {"Binary Space Partition Window Manager", "<config>/bspwm"},
+	"dunst":     {"Dunst, a lightweight notification daemon", "<config>/dunst"},
+	"emacs":     {"Emacs", "<home>/.emacs.d"},
+	"fish":      {"Friendly interactive shell", "<config>/fish"},
+	"git":       {"Git", "<config>/git"},
+	"i3":        {"i3 tiling window manager", "<config>/i3"},
+	"kitty":     {"Kitty, a GPU based terminal emulator", "<config>/kitty"},
+	"nvim":      {"Neovim", "<config>/nvim"},
+	"picom":     {"Picom, a compositor for X11", "<config>/picom"},
+	"polybar":   {"Polybar, a status bar", "<config>/polybar"},
+	"rofi":      {"Rofi, an application launcher", "<config>/rofi"},
+	"sxhkd":     {"Simple X hotkey daemon", "<config>/sxhkd"},
+	"tmux":      {"tmux, a terminal multiplexer", "<config>/tmux"},
+	"vim":       {"Vim", "<home>/.vim"},
+	"zsh":       {"Z shell", "<config>/zsh"},
+}
+
+const (
+	// Directories deeper than this inside of the repository aren't inferred
+	inferMaxDepth = 3
+)
+
+// Infer infers a dots config from an existing repository of dotfiles at root
+//
+// Directories of well-known applications, such as `nvim` or `zsh`, and directories only containing
+// dot-prefixed files, such as `.xinitrc`, are proposed as dotfiles. Directories only containing
+// dot-prefixed files are installed to `~` using `install_children`.
+//
+// License is detected from the license file in root and URL from the git remote of root,
+// either is left blank if it can't be detected.
+func Infer(root string) (*DotsConfig, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dotsConf := &DotsConfig{Version: CurrentVersion, Name: defaultName}
+	detected, err := DetectLicense(root)
+	if err != nil {
+		return nil, err
+	}
+	if detected != nil && detected.ID != "" {
+		dotsConf.License = detected.ID
+		if _, ok := spdxLicenses[detected.ID+"-only"]; ok {
+			dotsConf.License = detected.ID + "-only"
+		}
+	}
+
+	if dotsConf.URL, err = GitRemote(root); err != nil {
+		return nil, err
+	}
+	if name := repositoryName(dotsConf.URL); name != "" {
+		dotsConf.Name = name
+	}
+
+	if dotsConf.Dotfiles, err = inferDotfiles(root); err != nil {
+		return nil, err
+	}
+	return dotsConf, nil
+}
+
+// Walks root proposing a Dotfile for every recognized directory
+func inferDotfiles(root string) ([]Dotfile, error) {
+	dotfiles := []Dotfile{}
+	names := make(map[string]bool)
+
+	err := filepath.Walk(root, func(dir string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read `%s`: %w", dir, err)
+		}
+		if !info.IsDir() || dir == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if (strings.HasPrefix(info.Name(), ".") && info.Name() != ".config") || strings.Count(rel, "/") >= inferMaxDepth {
+			return filepath.SkipDir
+		}
+
+		children, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read directory `%s`: %w", dir, err)
+		}
+		app, known := knownApps[strings.ToLower(info.Name())]
+		collection := isDotCollection(children)
+		if !known && !collection {
+			return nil
+		}
+
+		dot := Dotfile{Name: info.Name(), Description: app.description, Destination: app.destination}
+		if names[dot.Name] {
+			dot.Name = strings.ReplaceAll(rel, "/", "-")
+		}
+		names[dot.Name] = true
+		if rel != dot.Name {
+			dot.Source = "<root>/" + rel
+		}
+		if !known {
+			dot.Description = fmt.Sprintf("TODO: describe %s", dot.Name)
+		}
+		if collection {
+			dot.Destination = "~"
+			dot.InstallChildren = true
+		}
+		dotfiles = append(dotfiles, dot)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dotfiles, nil
+}
+
+// Checks if every child is a dot-prefixed file, such as `.zshrc`
+func isDotCollection(children []os.FileInfo) bool {
+	for _, child := range children {
+		if !strings.HasPrefix(child.Name(), ".") || child.Name() == ".gitkeep" {
+			return false
+		}
+	}
+	return len(children) != 0
+}
+
+// Matches remotes written as `user@host:path`
+var scpRemoteRegexp = regexp.MustCompile(`^[\w.-]+@([\w.-]+):(.+)$`)
+
+// GitRemote the URL of the git remote of the repository containing dir, `origin` is preferred
+// otherwise the first remote is used
+//
+// Remotes written as `git@host:path` are converted to `https://host/path`. If dir has no remotes,
+// isn't inside of a git repository or git isn't installed the URL is blank.
+func GitRemote(dir string) (string, error) {
+	output, err := exec.Command("git", "-C", dir, "remote").Output()
+	if err != nil {
+		return "", nil
+	}
+	remotes := strings.Fields(string(output))
+	if len(remotes) == 0 {
+		return "", nil
+	}
+
+	remote := remotes[0]
+	for _, name := range remotes {
+		if name == "origin" {
+			remote = name
+		}
+	}
+	if output, err = exec.Command("git", "-C", dir, "remote", "get-url", remote).Output(); err != nil {
+		return "", fmt.Errorf("failed to get URL of git remote `%s`: %w", remote, err)
+	}
+
+	return normalizeRemote(strings.TrimSpace(string(output))), nil
+}
+
+// Converts a remote written as `git@host:path` to `https://host/path` without a `.git` suffix
+func normalizeRemote(url string) string {
+	if match := scpRemoteRegexp.FindStringSubmatch(url); match != nil {
+		url = fmt.Sprintf("https://%s/%s", match[1], match[2])
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+}
+
+// Name of a repository from its URL, `YourName/dotfiles` for `https://github.com/YourName/dotfiles`
+func repositoryName(url string) string {
+	parts := strings.Split(strings.Trim(url, "/"), "/")
+	if len(parts) < 4 || !strings.Contains(url, "://") {
+		return ""
+	}
+	return path.Join(parts[len(parts)-2], parts[len(parts)-1])
+}
+
+// Commented dots config based on `dots.template.yml`
+var scaffoldTemplate = template.Must(template.New(".dots.yml").Funcs(template.FuncMap{"yaml": yamlScalar}).Parse(`# This file is metadata that Dots will read to install
+# the dotfiles in your repository locally on a new machine.
+#
+# It was generated by ` + "`dots init`" + `, make sure to review every dotfile and run
+#
+# $ dots validate
+#
+# to ensure that your configuration file is valid.
+
+# Version of the dots configuration format this file is written in
+version: {{ .Version }}
+
+# Name of your dotfiles repository
+#
+# In the majority of cases should be ` + "`YourName/(dotfiles|dots|config)`" + `
+name: {{ yaml .Name }}
+
+# The license your dotfiles are licensed under
+#
+# This MUST be a SPDX license identifier or expression, for example ` + "`MIT`" + `
+# or ` + "`MIT OR Apache-2.0`" + `, for a list of identifiers consult https://spdx.org/licenses/
+{{- if .License }}
+license: {{ yaml .License }}
+{{- else }}
+#
+# TODO: no license file was recognized, if you're not sure which license consult https://choosealicense.com/
+license: ""
+{{- end }}
+
+# URL to your repository or upstream URL
+{{- if .URL }}
+URL: {{ yaml .URL }}
+{{- else }}
+#
+# TODO: no git remote was found
+URL: ""
+{{- end }}
+
+# List of dotfiles that will be installable and their required metadata
+#
+# source defaults to <root>/$name and destination defaults to XDG_CONFIG_HOME/$name,
+# install_children installs every child of source to destination instead of source itself.
+{{- if not .Dotfiles }}
+#
+# TODO: no dotfiles were recognized
+dotfiles: []
+{{- else }}
+dotfiles:
+{{- range .Dotfiles }}
+  - name: {{ yaml .Name }}
+    description: {{ yaml .Description }}
+{{- if .Source }}
+    source: {{ yaml .Source }}
+{{- end }}
+    destination: {{ if eq .Destination "~" }}"~"{{ else }}{{ yaml .Destination }}{{ end }}
+{{- if .InstallChildren }}
+    install_children: true
+{{- end }}
+{{- end }}
+{{- end }}
+`))
+
+// Scaffold renders dotsConf as a commented dots config, such as one inferred by `Infer`
+func Scaffold(dotsConf *DotsConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := scaffoldTemplate.Execute(&buf, dotsConf); err != nil {
+		return nil, fmt.Errorf("failed to render dots config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
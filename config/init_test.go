@@ -0,0 +1,99 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfer(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup init_test.go testing: %w", err)
+
+	dotsConfig, err := config.Infer(filepath.Join(testData, "init"))
+	assert.NoError(t, err)
+	assert.Equal(t, config.CurrentVersion, dotsConfig.Version)
+	assert.Equal(t, "GPL-3.0-only", dotsConfig.License)
+	assert.Equal(t, []config.Dotfile{
+		{
+			Name:        "kitty",
+			Description: "Kitty, a GPU based terminal emulator",
+			Source:      "<root>/.config/kitty",
+			Destination: "<config>/kitty",
+		},
+		{
+			Name:            "home",
+			Description:     "TODO: describe home",
+			Destination:     "~",
+			InstallChildren: true,
+		},
+		{
+			Name:        "nvim",
+			Description: "Neovim",
+			Destination: "<config>/nvim",
+		},
+		{
+			Name:            "zsh",
+			Description:     "Z shell",
+			Destination:     "~",
+			InstallChildren: true,
+		},
+	}, dotsConfig.Dotfiles)
+}
+
+func TestScaffold(t *testing.T) {
+	homeDir, err := os.UserHomeDir()
+	assert.NoErrorf(t, err, "failed to setup init_test.go testing can't locate `$HOME`: %w", err)
+
+	root := copyTestData(t, "init")
+	defer os.RemoveAll(root)
+	dotsConfig, err := config.Infer(root)
+	assert.NoError(t, err)
+	dotsConfig.Name = "YourName/dotfiles"
+	dotsConfig.URL = "https://github.com/YourName/dotfiles"
+
+	scaffolded, err := config.Scaffold(dotsConfig)
+	assert.NoError(t, err)
+
+	path := filepath.Join(root, ".dots.yml")
+	assert.NoError(t, ioutil.WriteFile(path, scaffolded, 0644))
+
+	parsed, err := config.ParseFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "YourName/dotfiles", parsed.Name)
+	assert.Len(t, parsed.Dotfiles, 4)
+	assert.Equal(t, filepath.Join(root, ".config", "kitty"), parsed.Dotfiles[0].Source)
+	assert.Equal(t, homeDir, parsed.Dotfiles[1].Destination)
+	assert.Nil(t, config.Validate(path))
+}
+
+func TestGitRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-init")
+	assert.NoErrorf(t, err, "failed to setup init_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, exec.Command("git", "-C", dir, "init", "-q").Run())
+	url, err := config.GitRemote(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "", url)
+
+	assert.NoError(t, exec.Command("git", "-C", dir, "remote", "add", "upstream", "https://gitlab.com/Someone/dots.git").Run())
+	assert.NoError(t, exec.Command("git", "-C", dir, "remote", "add", "origin", "git@github.com:YourName/dotfiles.git").Run())
+	url, err = config.GitRemote(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/YourName/dotfiles", url)
+
+	dotsConfig, err := config.Infer(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "YourName/dotfiles", dotsConfig.Name)
+	assert.Equal(t, "https://github.com/YourName/dotfiles", dotsConfig.URL)
+}
@@ -0,0 +1,448 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	spdxURL = "https://spdx.org/licenses/"
+)
+
+// License a SPDX license identifier and its metadata
+type License struct {
+	ID         string // SPDX license identifier for example `GPL-3.0-only`, or a `LicenseRef-` for custom licenses
+	Name       string // Full name of the license, empty if it isn't a commonly used license
+	Deprecated bool   // Deprecated by SPDX in favor of another identifier
+	Exception  bool   // If true this is a license exception used with `WITH`, rather than a license
+	Custom     bool   // If true this is a `LicenseRef-` that isn't part of the SPDX License List
+}
+
+// URL to the SPDX reference page of this License, empty for custom licenses
+func (l *License) URL() string {
+	if l.Custom {
+		return ""
+	}
+	return fmt.Sprintf("%s%s.html", spdxURL, l.ID)
+}
+
+// LicenseExpression a parsed SPDX license expression such as `MIT OR Apache-2.0`
+type LicenseExpression struct {
+	Expression string     // Expression as it was written
+	Canonical  string     // Expression with aliases, casing and deprecated identifiers replaced by canonical SPDX identifiers
+	Licenses   []*License // Every license and exception in the order they appear in Canonical
+}
+
+// IsCanonical checks if the expression was written using only canonical SPDX identifiers
+func (le *LicenseExpression) IsCanonical() bool {
+	return le.Expression == le.Canonical
+}
+
+// Includes checks if the expression references the license `id` or a variant of it,
+// for instance `GPL-3.0` is included by both `GPL-3.0-only` and `GPL-3.0-or-later`
+func (le *LicenseExpression) Includes(id string) bool {
+	for _, license := range le.Licenses {
+		if license.ID == id || license.ID == id+"-only" || license.ID == id+"-or-later" {
+			return true
+		}
+	}
+	return false
+}
+
+// LicenseError is an error dictating that a license expression isn't a valid SPDX license expression
+type LicenseError struct {
+	Expression string // Expression that failed to parse
+	Reason     string // Why it failed to parse
+}
+
+// Error returns a String stating why the license expression is invalid
+func (le *LicenseError) Error() string {
+	return fmt.Sprintf("license `%s` isn't a valid SPDX license expression: %s, consult %s", le.Expression, le.Reason, spdxURL)
+}
+
+// Common names for licenses and aliases used instead of SPDX identifiers, keys are normalized by `normalizeAlias`
+var licenseAliases = map[string]string{
+	"gpl2":            "GPL-2.0-only",
+	"gplv2":           "GPL-2.0-only",
+	"gplv20":          "GPL-2.0-only",
+	"gnugplv2":        "GPL-2.0-only",
+	"gpl2+":           "GPL-2.0-or-later",
+	"gplv2+":          "GPL-2.0-or-later",
+	"gpl3":            "GPL-3.0-only",
+	"gplv3":           "GPL-3.0-only",
+	"gplv30":          "GPL-3.0-only",
+	"gnugplv3":        "GPL-3.0-only",
+	"gpl3+":           "GPL-3.0-or-later",
+	"gplv3+":          "GPL-3.0-or-later",
+	"lgpl21":          "LGPL-2.1-only",
+	"lgplv21":         "LGPL-2.1-only",
+	"lgpl3":           "LGPL-3.0-only",
+	"lgplv3":          "LGPL-3.0-only",
+	"agpl3":           "AGPL-3.0-only",
+	"agplv3":          "AGPL-3.0-only",
+	"mitlicense":      "MIT",
+	"expat":           "MIT",
+	"apache":          "Apache-2.0",
+	"apache2":         "Apache-2.0",
+	"apache20":        "Apache-2.0",
+	"apachev2":        "Apache-2.0",
+	"apachelicense2":  "Apache-2.0",
+	"apachelicense20": "Apache-2.0",
+	"bsd":             "BSD-3-Clause",
+	"bsd3":            "BSD-3-Clause",
+	"newbsd":          "BSD-3-Clause",
+	"bsd2":            "BSD-2-Clause",
+	"simplifiedbsd":   "BSD-2-Clause",
+	"freebsd":         "BSD-2-Clause",
+	"mpl":             "MPL-2.0",
+	"mpl2":            "MPL-2.0",
+	"mozilla":         "MPL-2.0",
+	"cc0":             "CC0-1.0",
+	"publicdomain":    "Unlicense",
+	"theunlicense":    "Unlicense",
+	"wtfpl2":          "WTFPL",
+	"boost":           "BSL-1.0",
+	"eclipse":         "EPL-2.0",
+}
+
+// Deprecated identifiers that have a direct replacement
+var deprecatedReplacements = map[string]string{
+	"AGPL-1.0": "AGPL-1.0-only",
+	"AGPL-3.0": "AGPL-3.0-only",
+	"GFDL-1.1": "GFDL-1.1-only",
+	"GFDL-1.2": "GFDL-1.2-only",
+	"GFDL-1.3": "GFDL-1.3-only",
+	"GPL-1.0":  "GPL-1.0-only",
+	"GPL-2.0":  "GPL-2.0-only",
+	"GPL-3.0":  "GPL-3.0-only",
+	"LGPL-2.0": "LGPL-2.0-only",
+	"LGPL-2.1": "LGPL-2.1-only",
+	"LGPL-3.0": "LGPL-3.0-only",
+}
+
+// Full names of commonly used licenses
+var licenseNames = map[string]string{
+	"0BSD":              "BSD Zero Clause License",
+	"AGPL-3.0-only":     "GNU Affero General Public License v3.0 only",
+	"AGPL-3.0-or-later": "GNU Affero General Public License v3.0 or later",
+	"Apache-2.0":        "Apache License 2.0",
+	"BSD-2-Clause":      "BSD 2-Clause \"Simplified\" License",
+	"BSD-3-Clause":      "BSD 3-Clause \"New\" or \"Revised\" License",
+	"BSL-1.0":           "Boost Software License 1.0",
+	"CC0-1.0":           "Creative Commons Zero v1.0 Universal",
+	"CC-BY-4.0":         "Creative Commons Attribution 4.0 International",
+	"CC-BY-SA-4.0":      "Creative Commons Attribution Share Alike 4.0 International",
+	"EPL-2.0":           "Eclipse Public License 2.0",
+	"GPL-2.0-only":      "GNU General Public License v2.0 only",
+	"GPL-2.0-or-later":  "GNU General Public License v2.0 or later",
+	"GPL-3.0-only":      "GNU General Public License v3.0 only",
+	"GPL-3.0-or-later":  "GNU General Public License v3.0 or later",
+	"ISC":               "ISC License",
+	"LGPL-2.1-only":     "GNU Lesser General Public License v2.1 only",
+	"LGPL-2.1-or-later": "GNU Lesser General Public License v2.1 or later",
+	"LGPL-3.0-only":     "GNU Lesser General Public License v3.0 only",
+	"LGPL-3.0-or-later": "GNU Lesser General Public License v3.0 or later",
+	"MIT":               "MIT License",
+	"MPL-2.0":           "Mozilla Public License 2.0",
+	"Unlicense":         "The Unlicense",
+	"WTFPL":             "Do What The F*ck You Want To Public License",
+	"Zlib":              "zlib License",
+}
+
+// Normalizes a license alias by lower casing it and removing separators
+func normalizeAlias(alias string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "", ".", "").Replace(strings.ToLower(alias))
+}
+
+// Lookup a SPDX identifier, exceptions are only looked up when exception is true
+//
+// Aliases, the wrong casing and deprecated identifiers with a replacement all resolve to their canonical identifier.
+func lookupLicense(id string, exception bool) (*License, bool) {
+	ids := spdxLicenses
+	if exception {
+		ids = spdxExceptions
+	}
+
+	if deprecated, ok := ids[id]; ok {
+		if replacement, ok := deprecatedReplacements[id]; ok && !exception {
+			return lookupLicense(replacement, exception)
+		}
+		return &License{ID: id, Name: licenseNames[id], Deprecated: deprecated, Exception: exception}, true
+	}
+
+	for canonical := range ids {
+		if strings.EqualFold(canonical, id) {
+			return lookupLicense(canonical, exception)
+		}
+	}
+
+	if exception {
+		return nil, false
+	}
+
+	if canonical, ok := licenseAliases[normalizeAlias(id)]; ok {
+		return lookupLicense(canonical, exception)
+	}
+	return nil, false
+}
+
+type licenseParser struct {
+	expression string
+	tokens     []string
+	pos        int
+	licenses   []*License
+}
+
+// ParseLicense parses a SPDX license expression for example `MIT`, `GPL-3.0-or-later` or `MIT OR Apache-2.0`
+//
+// Common aliases such as `GPLv3` are accepted, in which case `LicenseExpression.IsCanonical` is false and
+// `LicenseExpression.Canonical` is the expression that should be used instead. The full syntax is described
+// in https://spdx.github.io/spdx-spec/v2.3/SPDX-license-expressions/
+func ParseLicense(expression string) (*LicenseExpression, error) {
+	// Aliases such as `GNU GPL v3` contain spaces and wouldn't survive tokenizing
+	if canonical, ok := licenseAliases[normalizeAlias(expression)]; ok {
+		license, _ := lookupLicense(canonical, false)
+		return &LicenseExpression{Expression: expression, Canonical: license.ID, Licenses: []*License{license}}, nil
+	}
+
+	parser := &licenseParser{expression: expression, tokens: tokenizeLicense(expression)}
+	if len(parser.tokens) == 0 {
+		return nil, &LicenseError{Expression: expression, Reason: "expression is empty"}
+	}
+
+	canonical, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos != len(parser.tokens) {
+		return nil, parser.errorf("unexpected `%s`", parser.tokens[parser.pos])
+	}
+	return &LicenseExpression{Expression: expression, Canonical: canonical, Licenses: parser.licenses}, nil
+}
+
+// Splits a license expression into identifiers, operators and parentheses
+func tokenizeLicense(expression string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(expression) {
+		for field != "" {
+			i := strings.IndexAny(field, "()")
+			switch {
+			case i == -1:
+				tokens, field = append(tokens, field), ""
+			case i == 0:
+				tokens, field = append(tokens, field[:1]), field[1:]
+			default:
+				tokens, field = append(tokens, field[:i]), field[i:]
+			}
+		}
+	}
+	return tokens
+}
+
+func (lp *licenseParser) errorf(format string, args ...interface{}) error {
+	return &LicenseError{Expression: lp.expression, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (lp *licenseParser) peek() string {
+	if lp.pos >= len(lp.tokens) {
+		return ""
+	}
+	return lp.tokens[lp.pos]
+}
+
+// Operators MUST be either all upper case or all lower case
+func (lp *licenseParser) acceptOperator(operator string) bool {
+	token := lp.peek()
+	if token == operator || token == strings.ToLower(operator) {
+		lp.pos++
+		return true
+	}
+	return false
+}
+
+func (lp *licenseParser) parseOr() (string, error) {
+	left, err := lp.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for lp.acceptOperator("OR") {
+		right, err := lp.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("%s OR %s", left, right)
+	}
+	return left, nil
+}
+
+func (lp *licenseParser) parseAnd() (string, error) {
+	left, err := lp.parseWith()
+	if err != nil {
+		return "", err
+	}
+	for lp.acceptOperator("AND") {
+		right, err := lp.parseWith()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("%s AND %s", left, right)
+	}
+	return left, nil
+}
+
+func (lp *licenseParser) parseWith() (string, error) {
+	if lp.peek() == "(" {
+		lp.pos++
+		inner, err := lp.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if lp.peek() != ")" {
+			return "", lp.errorf("missing closing parenthesis")
+		}
+		lp.pos++
+		return fmt.Sprintf("(%s)", inner), nil
+	}
+
+	license, err := lp.parseIdentifier(false)
+	if err != nil {
+		return "", err
+	}
+	if !lp.acceptOperator("WITH") {
+		return license, nil
+	}
+	exception, err := lp.parseIdentifier(true)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s WITH %s", license, exception), nil
+}
+
+func (lp *licenseParser) parseIdentifier(exception bool) (string, error) {
+	token := lp.peek()
+	switch token {
+	case "":
+		return "", lp.errorf("expected a license identifier, but expression ended")
+	case "(", ")", "AND", "OR", "WITH", "and", "or", "with":
+		return "", lp.errorf("expected a license identifier, but found `%s`", token)
+	}
+	lp.pos++
+
+	if strings.HasPrefix(token, "LicenseRef-") || strings.HasPrefix(token, "DocumentRef-") {
+		lp.licenses = append(lp.licenses, &License{ID: token, Custom: true})
+		return token, nil
+	}
+
+	orLater := !exception && strings.HasSuffix(token, "+") && len(token) > 1
+	id := token
+	if orLater {
+		if _, ok := lookupLicense(token, false); ok {
+			orLater = false
+		} else {
+			id = strings.TrimSuffix(token, "+")
+		}
+	}
+
+	license, ok := lookupLicense(id, exception)
+	if !ok {
+		kind := "license identifier"
+		if exception {
+			kind = "license exception identifier"
+		}
+		return "", lp.errorf("unknown SPDX %s `%s`", kind, token)
+	}
+	// `GPL-2.0+` is the deprecated form of `GPL-2.0-or-later`
+	if orLater && strings.HasSuffix(license.ID, "-only") {
+		license, _ = lookupLicense(strings.TrimSuffix(license.ID, "-only")+"-or-later", false)
+		orLater = false
+	}
+	lp.licenses = append(lp.licenses, license)
+	if orLater {
+		return license.ID + "+", nil
+	}
+	return license.ID, nil
+}
+
+// DetectedLicense a license detected from the text of a license file
+type DetectedLicense struct {
+	Path string // Path to the license file
+	ID   string // SPDX identifier without an `-only` or `-or-later` suffix, for instance `GPL-3.0`
+}
+
+// Names of license files that are checked in order
+var licenseFileNames = []string{
+	"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "LICENCE.md", "LICENCE.txt", "COPYING", "COPYING.md", "UNLICENSE",
+}
+
+// Phrases that identify a license, checked in order the first that has all phrases present wins.
+//
+// Licenses that reference one another, such as the GPL referencing the AGPL, are identified by
+// their title which has to appear within the first `licenseHeaderLen` characters.
+var licenseMatchers = []struct {
+	id      string
+	title   bool
+	phrases []string
+}{
+	{"AGPL-3.0", true, []string{"gnu affero general public license version 3"}},
+	{"LGPL-3.0", true, []string{"gnu lesser general public license version 3"}},
+	{"LGPL-2.1", true, []string{"gnu lesser general public license version 2.1"}},
+	{"GPL-3.0", true, []string{"gnu general public license version 3"}},
+	{"GPL-2.0", true, []string{"gnu general public license version 2"}},
+	{"MPL-2.0", true, []string{"mozilla public license", "version 2.0"}},
+	{"Apache-2.0", true, []string{"apache license version 2.0"}},
+	{"Unlicense", false, []string{"this is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", false, []string{"cc0 1.0 universal"}},
+	{"BSL-1.0", false, []string{"boost software license - version 1.0"}},
+	{"WTFPL", false, []string{"do what the fuck you want to"}},
+	{"MIT", false, []string{"permission is hereby granted, free of charge"}},
+	{"ISC", false, []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"BSD-3-Clause", false, []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", false, []string{"redistribution and use in source and binary forms"}},
+}
+
+const (
+	licenseHeaderLen = 512
+)
+
+// DetectLicense detects the license of a project from a license file such as `LICENSE` or `COPYING` in root
+//
+// If no license file is present nil is returned, if a license file is present but isn't recognized
+// `DetectedLicense.ID` is empty.
+func DetectLicense(root string) (*DetectedLicense, error) {
+	for _, name := range licenseFileNames {
+		path := filepath.Join(root, name)
+		bytes, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read license file `%s`: %w", path, err)
+		}
+
+		text := strings.Join(strings.Fields(strings.ToLower(string(bytes))), " ")
+		header := text
+		if len(header) > licenseHeaderLen {
+			header = header[:licenseHeaderLen]
+		}
+
+		detected := &DetectedLicense{Path: path}
+	matchers:
+		for _, matcher := range licenseMatchers {
+			search := text
+			if matcher.title {
+				search = header
+			}
+			for _, phrase := range matcher.phrases {
+				if !strings.Contains(search, phrase) {
+					continue matchers
+				}
+			}
+			detected.ID = matcher.id
+			break
+		}
+		return detected, nil
+	}
+	return nil, nil
+}
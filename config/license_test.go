@@ -0,0 +1,132 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLicense(t *testing.T) {
+	tests := []struct {
+		expression string
+		canonical  string
+		ids        []string
+	}{
+		{expression: "MIT", canonical: "MIT", ids: []string{"MIT"}},
+		{expression: "mit", canonical: "MIT", ids: []string{"MIT"}},
+		{expression: "GPLv3", canonical: "GPL-3.0-only", ids: []string{"GPL-3.0-only"}},
+		{expression: "GNU GPL v3", canonical: "GPL-3.0-only", ids: []string{"GPL-3.0-only"}},
+		{expression: "GPL-3.0", canonical: "GPL-3.0-only", ids: []string{"GPL-3.0-only"}},
+		{expression: "GPL-2.0+", canonical: "GPL-2.0-or-later", ids: []string{"GPL-2.0-or-later"}},
+		{expression: "Apache 2.0", canonical: "Apache-2.0", ids: []string{"Apache-2.0"}},
+		{expression: "MIT OR Apache-2.0", canonical: "MIT OR Apache-2.0", ids: []string{"MIT", "Apache-2.0"}},
+		{expression: "mit or apache2", canonical: "MIT OR Apache-2.0", ids: []string{"MIT", "Apache-2.0"}},
+		{
+			expression: "(MIT OR Apache-2.0) AND GPL-2.0-only WITH Classpath-exception-2.0",
+			canonical:  "(MIT OR Apache-2.0) AND GPL-2.0-only WITH Classpath-exception-2.0",
+			ids:        []string{"MIT", "Apache-2.0", "GPL-2.0-only", "Classpath-exception-2.0"},
+		},
+		{expression: "LicenseRef-Custom", canonical: "LicenseRef-Custom", ids: []string{"LicenseRef-Custom"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := config.ParseLicense(test.expression)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.canonical, expression.Canonical)
+			assert.Equal(t, test.expression == test.canonical, expression.IsCanonical())
+
+			var ids []string
+			for _, license := range expression.Licenses {
+				ids = append(ids, license.ID)
+			}
+			assert.Equal(t, test.ids, ids)
+		})
+	}
+}
+
+func TestParseLicenseInvalid(t *testing.T) {
+	tests := []struct {
+		expression string
+		reason     string
+	}{
+		{expression: "", reason: "expression is empty"},
+		{expression: "Not-A-License", reason: "unknown SPDX license identifier `Not-A-License`"},
+		{expression: "MIT OR", reason: "expected a license identifier, but expression ended"},
+		{expression: "MIT AND OR Apache-2.0", reason: "expected a license identifier, but found `OR`"},
+		{expression: "(MIT OR Apache-2.0", reason: "missing closing parenthesis"},
+		{expression: "MIT Apache-2.0", reason: "unexpected `Apache-2.0`"},
+		{expression: "GPL-2.0-only WITH MIT", reason: "unknown SPDX license exception identifier `MIT`"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := config.ParseLicense(test.expression)
+			assert.Equal(t, &config.LicenseError{Expression: test.expression, Reason: test.reason}, err)
+		})
+	}
+}
+
+func TestLicenseIncludes(t *testing.T) {
+	tests := []struct {
+		expression string
+		id         string
+		included   bool
+	}{
+		{expression: "GPL-3.0-only", id: "GPL-3.0", included: true},
+		{expression: "MIT OR GPL-3.0-or-later", id: "GPL-3.0", included: true},
+		{expression: "MIT", id: "MIT", included: true},
+		{expression: "MIT-0", id: "MIT", included: false},
+		{expression: "BSD-3-Clause-Clear", id: "BSD-3-Clause", included: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := config.ParseLicense(test.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, test.included, expression.Includes(test.id))
+		})
+	}
+}
+
+func TestDetectLicense(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup license_test.go testing: %w", err)
+
+	detected, err := config.DetectLicense(filepath.Dir(testData))
+	assert.NoError(t, err)
+	assert.Equal(t, &config.DetectedLicense{Path: filepath.Join(filepath.Dir(testData), "LICENSE"), ID: "GPL-3.0"}, detected)
+
+	detected, err = config.DetectLicense(testData)
+	assert.NoError(t, err)
+	assert.Nil(t, detected)
+
+	dir, err := ioutil.TempDir("", "dots-license")
+	assert.NoErrorf(t, err, "failed to setup license_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	mit := "MIT License\n\nCopyright (c) 2020 YourName\n\nPermission is hereby granted, free of charge, to any person obtaining a copy"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "LICENSE.md"), []byte(mit), 0644))
+	detected, err = config.DetectLicense(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &config.DetectedLicense{Path: filepath.Join(dir, "LICENSE.md"), ID: "MIT"}, detected)
+
+	conf := "name: YourName/dotfiles\nlicense: GPL-3.0-only\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".dots.yml"), []byte(conf), 0644))
+	validErr := config.Validate(filepath.Join(dir, ".dots.yml"))
+	assert.Equal(t, &config.ValidationError{
+		Warnings: []*config.Warning{
+			{
+				Message:        "license `GPL-3.0-only` doesn't match license file `" + filepath.Join(dir, "LICENSE.md") + "` which appears to be `MIT`",
+				Recommendation: "make license and the license file agree",
+				Rule:           "license-file-mismatch",
+			},
+		},
+	}, validErr)
+}
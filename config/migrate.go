@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CurrentVersion the version of the dots config format that this version of dots reads and writes
+	CurrentVersion = 1
+)
+
+// VersionError is an error dictating that a dots config is written for a newer version of dots
+type VersionError struct {
+	Version int // Version of the dots config
+}
+
+// Error returns a String stating the version isn't supported
+func (ve *VersionError) Error() string {
+	return fmt.Sprintf("dots config version `%d` is newer than the newest supported version `%d`, upgrade dots to read it", ve.Version, CurrentVersion)
+}
+
+// migration upgrades a dots config from one version to the next
+//
+// Migrations only change the schema, the version itself is updated after the migration is applied.
+type migration struct {
+	description string
+	migrate     func(doc *document) error
+}
+
+// Every migration, the migration at index `i` upgrades a dots config from version `i` to `i + 1`
+var migrations = []migration{
+	{
+		// Configs written before `version` existed are version 0, the schema is otherwise identical
+		description: "set `version`, dots configs without a version are version 0",
+		migrate:     func(doc *document) error { return nil },
+	},
+}
+
+// Gets the version of a dots config, a dots config without a version is version 0
+func (doc *document) version() (int, error) {
+	_, value := mappingValue(doc.root, "version")
+	if isNull(value) {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value.Value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("version `%s` isn't a positive integer", value.Value)
+	}
+	return version, nil
+}
+
+// Sets the version of a dots config, inserting it before the first key if it isn't present
+func (doc *document) setVersion(version int) {
+	text := strconv.Itoa(version)
+	if doc.root == nil || len(doc.root.Content) == 0 {
+		doc.insertLine(1, "version: "+text)
+		return
+	}
+
+	key, value := mappingValue(doc.root, "version")
+	if key != nil {
+		doc.setValue(key, value, text)
+		return
+	}
+
+	// Keep the comment above the first key attached to it
+	first := doc.root.Content[0]
+	if first.HeadComment == "" {
+		doc.insertKey(first.Line, first.Column, "version", text)
+		return
+	}
+	line := first.Line - strings.Count(first.HeadComment, "\n") - 1
+	doc.insertKey(line, first.Column, "version", text)
+	doc.insertLine(line, "")
+}
+
+// Migrates the contents of a dots config to CurrentVersion, returning the
+// migrated contents and a description of every migration applied
+func migrate(bytes []byte) ([]byte, []string, error) {
+	var changes []string
+	for {
+		doc, err := parseDocument(bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		version, err := doc.version()
+		if err != nil {
+			return nil, nil, err
+		}
+		if version > CurrentVersion {
+			return nil, nil, &VersionError{Version: version}
+		}
+		if version == CurrentVersion {
+			return bytes, changes, nil
+		}
+
+		migration := migrations[version]
+		if err = migration.migrate(doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to migrate from version `%d` to `%d`: %w", version, version+1, err)
+		}
+		doc.setVersion(version + 1)
+		bytes = doc.bytes()
+		changes = append(changes, fmt.Sprintf("version `%d` to `%d`: %s", version, version+1, migration.description))
+	}
+}
+
+// Migrate migrates the dots config at path to CurrentVersion
+//
+// `ParseFile` migrates every dots config it reads in memory, Migrate is only
+// needed to rewrite the dots config itself.
+func Migrate(path string) (*Rewrite, error) {
+	bytes, err := readRewritable(path)
+	if err != nil {
+		return nil, err
+	}
+
+	migrated, changes, err := migrate(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate `%s`: %w", path, err)
+	}
+	return &Rewrite{Path: path, Original: bytes, Rewritten: migrated, Changes: changes}, nil
+}
@@ -0,0 +1,52 @@
+package config_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup migrate_test.go testing: %w", err)
+
+	expected, err := ioutil.ReadFile(filepath.Join(testData, "migrate", "v1.yml"))
+	assert.NoErrorf(t, err, "failed to setup migrate_test.go testing can't read `v1.yml`: %w", err)
+
+	rewrite, err := config.Migrate(filepath.Join(testData, "migrate", "v0.yml"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(rewrite.Rewritten))
+	assert.Equal(t, []string{"version `0` to `1`: set `version`, dots configs without a version are version 0"}, rewrite.Changes)
+
+	rewrite, err = config.Migrate(filepath.Join(testData, "migrate", "v1.yml"))
+	assert.NoError(t, err)
+	assert.False(t, rewrite.Changed())
+	assert.Equal(t, expected, rewrite.Rewritten)
+}
+
+func TestMigrateParseFile(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup migrate_test.go testing: %w", err)
+
+	v0, err := config.ParseFile(filepath.Join(testData, "migrate", "v0.yml"))
+	assert.NoError(t, err)
+	v1, err := config.ParseFile(filepath.Join(testData, "migrate", "v1.yml"))
+	assert.NoError(t, err)
+	assert.Equal(t, v1, v0)
+	assert.Equal(t, config.CurrentVersion, v0.Version)
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup migrate_test.go testing: %w", err)
+
+	_, err = config.Migrate(filepath.Join(testData, "invalid-version-newer.yml"))
+	var versionErr *config.VersionError
+	if assert.True(t, errors.As(err, &versionErr)) {
+		assert.Equal(t, 1000, versionErr.Version)
+	}
+}
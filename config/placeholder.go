@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// xdgDir a base directory from the XDG Base Directory Specification
+//
+// https://specifications.freedesktop.org/basedir-spec/basedir-spec-latest.html
+type xdgDir struct {
+	env      string // Environment variable that sets the directory
+	fallback string // Path relative to the home directory used if env is unset, empty or relative
+}
+
+// Placeholders that can start a Dotfile's source or destination, `<root>` is handled separately since
+// it's only valid in source
+var placeholders = map[string]xdgDir{
+	"home":   {},
+	"config": {"XDG_CONFIG_HOME", ".config"},
+	"data":   {"XDG_DATA_HOME", ".local/share"},
+	"state":  {"XDG_STATE_HOME", ".local/state"},
+	"cache":  {"XDG_CACHE_HOME", ".cache"},
+	"bin":    {"XDG_BIN_HOME", ".local/bin"},
+}
+
+// Matches anything that looks like a placeholder, such as `<config>`
+var placeholderRegexp = regexp.MustCompile(`<[^<>/\\\s]*>`)
+
+// PlaceholderError is an error dictating that a Dotfile's source or destination uses a placeholder incorrectly
+type PlaceholderError struct {
+	Name        string // Name of the Dotfile
+	Field       string // Field the placeholder is in, either `source` or `destination`
+	Value       string // Value of the field
+	Placeholder string // Placeholder itself, such as `<config>`
+	Reason      string // Reason the placeholder isn't valid
+}
+
+// Error returns a String stating which placeholder isn't valid and why
+func (pe *PlaceholderError) Error() string {
+	return fmt.Sprintf("dotfile `%s` %s `%s` uses placeholder `%s` %s", pe.Name, pe.Field, pe.Value, pe.Placeholder, pe.Reason)
+}
+
+// Placeholders names every placeholder in order, such as `<config>`
+func Placeholders() []string {
+	names := []string{"<root>"}
+	for name := range placeholders {
+		names = append(names, fmt.Sprintf("<%s>", name))
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// ResolvePlaceholder resolves a placeholder's name, such as `data` for `<data>`, to the directory it stands for
+//
+// XDG directories are taken from their environment variable, and if it's unset or not an absolute path
+// fallback to their default in the home directory, such as `~/.local/share` for `<data>`.
+func ResolvePlaceholder(name string) (string, error) {
+	dir, ok := placeholders[name]
+	if !ok {
+		return "", fmt.Errorf("unknown placeholder `<%s>`", name)
+	}
+	if value := os.Getenv(dir.env); dir.env != "" && filepath.IsAbs(value) {
+		return filepath.Clean(value), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user home directory: %w", err)
+	}
+	return filepath.Join(home, filepath.FromSlash(dir.fallback)), nil
+}
+
+// Expands the placeholder that value starts with, projectRoot replaces `<root>` which is only valid in source
+//
+// A placeholder MUST be the first element of the path, such as `<data>/fonts`. Unknown placeholders and
+// placeholders anywhere else are a `PlaceholderError`.
+func (dot *Dotfile) expandPlaceholder(field, value, projectRoot string) (string, error) {
+	locations := placeholderRegexp.FindAllStringIndex(value, -1)
+	if len(locations) == 0 {
+		return value, nil
+	}
+
+	placeholderErr := &PlaceholderError{Name: dot.Name, Field: field, Value: value}
+	for _, location := range locations {
+		placeholder := value[location[0]:location[1]]
+		name := placeholder[1 : len(placeholder)-1]
+		if _, ok := placeholders[name]; !ok && name != "root" {
+			placeholderErr.Placeholder = placeholder
+			placeholderErr.Reason = fmt.Sprintf("which isn't one of `%s`", strings.Join(Placeholders(), "`, `"))
+			return "", placeholderErr
+		}
+	}
+
+	start, end := locations[0][0], locations[0][1]
+	placeholder := value[start:end]
+	rest := value[end:]
+	if len(locations) > 1 || start != 0 || (rest != "" && rest[0] != '/' && rest[0] != os.PathSeparator) {
+		if start == 0 && len(locations) > 1 {
+			placeholder = value[locations[1][0]:locations[1][1]]
+		}
+		placeholderErr.Placeholder = placeholder
+		placeholderErr.Reason = "which can only be at the start followed by `/`, such as `<config>/nvim`"
+		return "", placeholderErr
+	}
+
+	if placeholder == "<root>" {
+		if field != "source" {
+			placeholderErr.Placeholder = placeholder
+			placeholderErr.Reason = "which is only valid in source"
+			return "", placeholderErr
+		}
+		return filepath.Join(projectRoot, rest), nil
+	}
+
+	dir, err := ResolvePlaceholder(placeholder[1 : len(placeholder)-1])
+	if err != nil {
+		return "", fmt.Errorf("dotfile `%s` failed to expand %s `%s`: %w", dot.Name, field, value, err)
+	}
+	return filepath.Join(dir, rest), nil
+}
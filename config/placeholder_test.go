@@ -0,0 +1,103 @@
+package config_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePlaceholder(t *testing.T) {
+	homeDir, err := os.UserHomeDir()
+	assert.NoErrorf(t, err, "failed to setup placeholder_test.go testing can't locate `$HOME`: %w", err)
+
+	env := map[string]string{}
+	for _, name := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "XDG_BIN_HOME"} {
+		env[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range env {
+			os.Setenv(name, value)
+		}
+	}()
+
+	assert.NoError(t, os.Setenv("XDG_CONFIG_HOME", "/custom/config"))
+	assert.NoError(t, os.Setenv("XDG_DATA_HOME", ""))
+	assert.NoError(t, os.Setenv("XDG_STATE_HOME", "relative/state"))
+	assert.NoError(t, os.Unsetenv("XDG_CACHE_HOME"))
+	assert.NoError(t, os.Unsetenv("XDG_BIN_HOME"))
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "home", expected: homeDir},
+		{name: "config", expected: filepath.FromSlash("/custom/config")},
+		{name: "data", expected: filepath.Join(homeDir, ".local", "share")},
+		{name: "state", expected: filepath.Join(homeDir, ".local", "state")},
+		{name: "cache", expected: filepath.Join(homeDir, ".cache")},
+		{name: "bin", expected: filepath.Join(homeDir, ".local", "bin")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := config.ResolvePlaceholder(test.name)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, dir)
+		})
+	}
+
+	_, err = config.ResolvePlaceholder("root")
+	assert.Error(t, err)
+}
+
+func TestParsePlaceholders(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup placeholder_test.go testing: %w", err)
+
+	homeDir, err := os.UserHomeDir()
+	assert.NoErrorf(t, err, "failed to setup placeholder_test.go testing can't locate `$HOME`: %w", err)
+
+	dataDir, err := config.ResolvePlaceholder("data")
+	assert.NoError(t, err)
+
+	dotsConfig, err := config.ParseFile(filepath.Join(testData, "placeholders.yml"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(testData, "bspwm"), dotsConfig.Dotfiles[0].Source)
+	assert.Equal(t, filepath.Join(dataDir, "bspwm"), dotsConfig.Dotfiles[0].Destination)
+	assert.Equal(t, homeDir, dotsConfig.Dotfiles[1].Destination)
+}
+
+func TestParsePlaceholderNotAtStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-placeholder")
+	assert.NoErrorf(t, err, "failed to setup placeholder_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		destination string
+		placeholder string
+	}{
+		{destination: "~/<config>/nvim", placeholder: "<config>"},
+		{destination: "<config>nvim", placeholder: "<config>"},
+		{destination: "<config>/<data>", placeholder: "<data>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.destination, func(t *testing.T) {
+			path := filepath.Join(dir, ".dots.yml")
+			contents := fmt.Sprintf("license: MIT\ndotfiles:\n  - name: nvim\n    destination: %s\n", test.destination)
+			assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+			_, err := config.ParseFile(path)
+			placeholderErr, ok := err.(*config.PlaceholderError)
+			assert.True(t, ok)
+			if ok {
+				assert.Equal(t, test.placeholder, placeholderErr.Placeholder)
+			}
+		})
+	}
+}
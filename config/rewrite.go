@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Rewrite a rewrite of a dots config such as fixing or migrating it, nothing is written until `Rewrite.Write` is called
+type Rewrite struct {
+	Path      string   // Path to the dots config
+	Original  []byte   // Contents before rewriting
+	Rewritten []byte   // Contents after rewriting
+	Changes   []string // Description of every change made
+}
+
+// Changed checks if any changes were made
+func (r *Rewrite) Changed() bool {
+	return len(r.Changes) != 0
+}
+
+// Diff returns a unified diff between the original and rewritten contents
+func (r *Rewrite) Diff() (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(r.Original)),
+		B:        difflib.SplitLines(string(r.Rewritten)),
+		FromFile: r.Path,
+		ToFile:   r.Path,
+		Context:  3,
+	})
+}
+
+// Write writes the rewritten contents to Path
+func (r *Rewrite) Write() error {
+	info, err := os.Stat(r.Path)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(r.Path, r.Rewritten, info.Mode()); err != nil {
+		return fmt.Errorf("failed to write `%s`: %w", r.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Severity how violations of a Rule are reported
+type Severity int
+
+const (
+	// SeverityOff the Rule isn't checked
+	SeverityOff Severity = iota
+	// SeverityWarning violations are reported as a Warning
+	SeverityWarning
+	// SeverityError violations are reported as the Err of a ValidationError
+	SeverityError
+)
+
+// Names of every Severity as they're written in a user config
+var severityNames = []string{"off", "warning", "error"}
+
+// String returns the name of the Severity, such as `warning`
+func (severity Severity) String() string {
+	if severity < 0 || int(severity) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(severity))
+	}
+	return severityNames[severity]
+}
+
+// ParseSeverity parses the name of a Severity, one of `off`, `warning` or `error`
+func ParseSeverity(name string) (Severity, error) {
+	for i, severityName := range severityNames {
+		if strings.EqualFold(name, severityName) {
+			return Severity(i), nil
+		}
+	}
+	return SeverityOff, fmt.Errorf("unknown severity `%s`, expected one of `off`, `warning` or `error`", name)
+}
+
+// When a Rule is checked
+type rulePhase int
+
+const (
+	phaseConfig   rulePhase = iota // Checked once for the dots config before any dotfile
+	phaseSources                   // Checked once for the sources of every dotfile, before any other rule can stop validation
+	phaseDotfile                   // Checked for each dotfile in order
+	phaseDotfiles                  // Checked once comparing every dotfile after every dotfile has been checked
+)
+
+// violation a single violation of a Rule
+type violation struct {
+	err            error  // What's wrong, its message is used as the Warning's message
+	recommendation string // Recommendation specific to this violation, if empty the Rule's Hint is used
+}
+
+// Rule a named check of a dots config
+type Rule struct {
+	ID          string   // Identifies the Rule in user configs and `# dots:ignore` comments
+	Severity    Severity // Default severity
+	Description string   // What the Rule checks
+	Hint        string   // How to fix a violation
+
+	phase rulePhase
+	// Checks the dots config, or the dotfile at index for phaseDotfile, errors returned are failures
+	// to check the Rule rather than violations
+	check func(v *validator, index int) ([]violation, error)
+}
+
+// Every Rule in the order they're checked
+var rules = []*Rule{
+	{
+		ID:          "name-blank",
+		Severity:    SeverityWarning,
+		Description: "the dots config's name is blank",
+		Hint:        "set name to default value `YourName/dotfiles`",
+		phase:       phaseConfig,
+		check: func(v *validator, _ int) ([]violation, error) {
+			if v.dotsConf.Name != "" {
+				return nil, nil
+			}
+			return []violation{{err: errors.New("dots config name shouldn't be left blank, isn't directly installable")}}, nil
+		},
+	},
+	{
+		ID:          "license-missing",
+		Severity:    SeverityError,
+		Description: "the dots config has no license",
+		Hint:        "set license to the SPDX identifier of your license, such as `MIT`",
+		phase:       phaseConfig,
+		check: func(v *validator, _ int) ([]violation, error) {
+			if v.dotsConf.License != "" {
+				return nil, nil
+			}
+			return []violation{{err: errors.New("license is required, if you're not sure which license consult https://choosealicense.com/")}}, nil
+		},
+	},
+	{
+		ID:          "license-invalid",
+		Severity:    SeverityError,
+		Description: "the license isn't a valid SPDX license expression",
+		Hint:        "run `dots license` and consult https://spdx.org/licenses/ for valid identifiers",
+		phase:       phaseConfig,
+		check: func(v *validator, _ int) ([]violation, error) {
+			if v.dotsConf.License == "" {
+				return nil, nil
+			}
+			if _, err := ParseLicense(v.dotsConf.License); err != nil {
+				return []violation{{err: err}}, nil
+			}
+			return nil, nil
+		},
+	},
+	{
+		ID:          "license-not-canonical",
+		Severity:    SeverityWarning,
+		Description: "the license uses aliases rather than SPDX license identifiers",
+		Hint:        "run `dots validate --fix` to use SPDX license identifiers",
+		phase:       phaseConfig,
+		check: func(v *validator, _ int) ([]violation, error) {
+			expression := v.license()
+			if expression == nil || expression.IsCanonical() {
+				return nil, nil
+			}
+			return []violation{{
+				err:            fmt.Errorf("license `%s` isn't written using SPDX license identifiers", expression.Expression),
+				recommendation: fmt.Sprintf("set license to `%s`", expression.Canonical),
+			}}, nil
+		},
+	},
+	{
+		ID:          "license-deprecated",
+		Severity:    SeverityWarning,
+		Description: "the license uses SPDX license identifiers that are deprecated",
+		Hint:        "replace deprecated identifiers, for instance `GPL-3.0` with `GPL-3.0-only`",
+		phase:       phaseConfig,
+		check: func(v *validator, _ int) ([]violation, error) {
+			expression := v.license()
+			if expression == nil {
+				return nil, nil
+			}
+			var violations []violation
+			for _, license := range expression.Licenses {
+				if license.Deprecated {
+					violations = append(violations, violation{
+						err:            fmt.Errorf("license `%s` is deprecated by SPDX", license.ID),
+						recommendation: fmt.Sprintf("consult %s for its replacement", license.URL()),
+					})
+				}
+			}
+			return violations, nil
+		},
+	},
+	{
+		ID:          "license-file-mismatch",
+		Severity:    SeverityWarning,
+		Description: "the license doesn't agree with the license file in the project root",
+		Hint:        "make license and the license file agree",
+		phase:       phaseConfig,
+		check: func(v *validator, _ int) ([]violation, error) {
+			expression := v.license()
+			if expression == nil {
+				return nil, nil
+			}
+			detected, err := DetectLicense(v.root)
+			if err != nil {
+				return nil, err
+			}
+			if detected == nil || detected.ID == "" || expression.Includes(detected.ID) {
+				return nil, nil
+			}
+			return []violation{{err: fmt.Errorf("license `%s` doesn't match license file `%s` which appears to be `%s`", expression.Expression, detected.Path, detected.ID)}}, nil
+		},
+	},
+	{
+		ID:          "vcs-unsupported",
+		Severity:    SeverityWarning,
+		Description: "the project is in a Mercurial, Subversion or Bazaar repository, which the git rules can't check",
+		Hint:        "check yourself that every source is committed and the URL matches the repository",
+		phase:       phaseConfig,
+		check: func(v *validator, _ int) ([]violation, error) {
+			if v.repository() != nil || v.vcsName == "" || v.vcsName == "Git" {
+				return nil, nil
+			}
+			return []violation{{err: fmt.Errorf("project root `%s` is in a %s repository, `url-remote-mismatch`, `source-uncommitted` and `source-modified` only check git repositories", v.root, v.vcsName)}}, nil
+		},
+	},
+	{
+		ID:          "url-remote-mismatch",
+		Severity:    SeverityWarning,
+		Description: "the URL doesn't match any git remote of the repository, only checked in git repositories",
+		Hint:        "set URL to the URL of the repository's remote",
+		phase:       phaseConfig,
+		check: func(v *validator, _ int) ([]violation, error) {
+			repo := v.repository()
+			if v.dotsConf.URL == "" || repo == nil {
+				return nil, nil
+			}
+			remotes, err := repo.remotes()
+			if err != nil || len(remotes) == 0 {
+				return nil, err
+			}
+			url := normalizeRemote(v.dotsConf.URL)
+			for _, remote := range remotes {
+				if strings.EqualFold(url, remote) {
+					return nil, nil
+				}
+			}
+			remote, err := GitRemote(v.root)
+			if err != nil {
+				return nil, err
+			}
+			return []violation{{
+				err:            fmt.Errorf("URL `%s` doesn't match any git remote, remotes are `%s`", v.dotsConf.URL, strings.Join(remotes, "`, `")),
+				recommendation: fmt.Sprintf("set URL to `%s`", remote),
+			}}, nil
+		},
+	},
+	{
+		ID:          "secret",
+		Severity:    SeverityError,
+		Description: "a dotfile's source contains what appears to be a private key, token or password",
+		Hint:        "remove the secret and rotate it, or allow a false positive with a `dots:allow-secret` comment on its line or in `allow_secrets`",
+		phase:       phaseSources,
+		check: func(v *validator, _ int) ([]violation, error) {
+			secretErr := &SecretError{}
+			for i, dot := range v.dotsConf.Dotfiles {
+				if !v.scansSource(i, "secret") {
+					continue
+				}
+				findings, err := ScanSecrets(dot.Source, v.secretAllowed)
+				if err != nil {
+					return nil, err
+				}
+				if len(findings) != 0 {
+					secretErr.Dotfiles = append(secretErr.Dotfiles, DotfileSecrets{Name: dot.Name, Findings: findings})
+				}
+			}
+			if len(secretErr.Dotfiles) == 0 {
+				return nil, nil
+			}
+			return []violation{{err: secretErr}}, nil
+		},
+	},
+	{
+		ID:          "secret-unscanned",
+		Severity:    SeverityWarning,
+		Description: "a dotfile's source has files too large to scan for secrets",
+		Hint:        "check the file for secrets yourself, files larger than 1MiB aren't scanned",
+		phase:       phaseSources,
+		check: func(v *validator, _ int) ([]violation, error) {
+			var violations []violation
+			for i, dot := range v.dotsConf.Dotfiles {
+				if !v.scansSource(i, "secret-unscanned") {
+					continue
+				}
+				unscanned, err := UnscannedFiles(dot.Source)
+				if err != nil {
+					return nil, err
+				}
+				for _, path := range unscanned {
+					violations = append(violations, violation{err: fmt.Errorf("dotfile `%s` `%s` is too large to scan for secrets", dot.Name, path)})
+				}
+			}
+			return violations, nil
+		},
+	},
+	{
+		ID:          "dotfile-name-blank",
+		Severity:    SeverityError,
+		Description: "a dotfile's name is blank",
+		Hint:        "set the dotfile's name, it's used to install it",
+		phase:       phaseDotfile,
+		check: func(v *validator, index int) ([]violation, error) {
+			if v.dotsConf.Dotfiles[index].Name != "" {
+				return nil, nil
+			}
+			return []violation{{err: fmt.Errorf("dotfile number `%d` name is blank, but field is required", index+1)}}, nil
+		},
+	},
+	{
+		ID:          "source-missing",
+		Severity:    SeverityError,
+		Description: "a dotfile's source doesn't exist",
+		Hint:        "create the source or set source to where the dotfile is in your repository",
+		phase:       phaseDotfile,
+		check: func(v *validator, index int) ([]violation, error) {
+			dot := v.dotsConf.Dotfiles[index]
+			if _, err := os.Stat(dot.Source); !os.IsNotExist(err) {
+				return nil, nil
+			}
+			return []violation{{err: fmt.Errorf("dotfile `%s` source field `%s` does not exist", dot.Name, dot.Source)}}, nil
+		},
+	},
+	{
+		ID:          "source-uncommitted",
+		Severity:    SeverityError,
+		Description: "a dotfile's source has files that are untracked, ignored or not committed by git, only checked in git repositories",
+		Hint:        "commit the files, otherwise they're missing for anyone that installs the dotfile",
+		phase:       phaseDotfile,
+		check: func(v *validator, index int) ([]violation, error) {
+			return v.sourceViolations(index, FileUntracked, FileIgnored, FileUncommitted)
+		},
+	},
+	{
+		ID:          "source-modified",
+		Severity:    SeverityWarning,
+		Description: "a dotfile's source has files with modifications that aren't committed, only checked in git repositories",
+		Hint:        "commit or discard the modifications, anyone that installs the dotfile gets what's committed",
+		phase:       phaseDotfile,
+		check: func(v *validator, index int) ([]violation, error) {
+			return v.sourceViolations(index, FileModified)
+		},
+	},
+	{
+		ID:          "description-blank",
+		Severity:    SeverityWarning,
+		Description: "a dotfile's description is blank",
+		Hint:        "describe the dotfile, `dots validate --fix` adds a placeholder",
+		phase:       phaseDotfile,
+		check: func(v *validator, index int) ([]violation, error) {
+			dot := v.dotsConf.Dotfiles[index]
+			if dot.Description != "" {
+				return nil, nil
+			}
+			return []violation{{err: fmt.Errorf("dotfile `%s` description shouldn't be left blank", dot.Name)}}, nil
+		},
+	},
+	{
+		ID:          "install-children-empty",
+		Severity:    SeverityError,
+		Description: "a dotfile with `install_children` has a source without children",
+		Hint:        "add children to the source or remove `install_children`",
+		phase:       phaseDotfile,
+		check: func(v *validator, index int) ([]violation, error) {
+			dot := v.dotsConf.Dotfiles[index]
+			if !dot.InstallChildren {
+				return nil, nil
+			}
+			files, err := ioutil.ReadDir(dot.Source)
+			if os.IsNotExist(err) {
+				return nil, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("dotfile `%s` failed to read directory `%s`: %w", dot.Name, dot.Source, err)
+			}
+			if len(files) != 0 {
+				return nil, nil
+			}
+			return []violation{{err: fmt.Errorf("dotfile `%s` has `install_children` set, but has 0 children in source `%s`", dot.Name, dot.Source)}}, nil
+		},
+	},
+	{
+		ID:          "duplicate-name",
+		Severity:    SeverityError,
+		Description: "two dotfiles have the same name",
+		Hint:        "rename one of the dotfiles, names identify dotfiles when installing",
+		phase:       phaseDotfiles,
+		check: func(v *validator, _ int) ([]violation, error) {
+			var violations []violation
+			v.duplicates("duplicate-name", func(dot *Dotfile) string { return dot.Name }, func(first, second int) {
+				err := fmt.Errorf("dotfiles with index `%d` and `%d` both have the same name `%s`", second+1, first+1, v.dotsConf.Dotfiles[second].Name)
+				violations = append(violations, violation{err: err})
+			})
+			return violations, nil
+		},
+	},
+	{
+		ID:          "duplicate-description",
+		Severity:    SeverityWarning,
+		Description: "two dotfiles have the same description",
+		Hint:        "describe what's specific to each dotfile",
+		phase:       phaseDotfiles,
+		check: func(v *validator, _ int) ([]violation, error) {
+			var violations []violation
+			v.duplicates("duplicate-description", func(dot *Dotfile) string { return dot.Description }, func(first, second int) {
+				firstDot, secondDot := v.dotsConf.Dotfiles[first], v.dotsConf.Dotfiles[second]
+				err := fmt.Errorf("dotfiles %s and %s have the same description `%s`", firstDot.Name, secondDot.Name, secondDot.Description)
+				violations = append(violations, violation{err: err})
+			})
+			return violations, nil
+		},
+	},
+	{
+		ID:          "duplicate-source",
+		Severity:    SeverityError,
+		Description: "two dotfiles have the same source",
+		Hint:        "merge the dotfiles or point each at its own source",
+		phase:       phaseDotfiles,
+		check: func(v *validator, _ int) ([]violation, error) {
+			var violations []violation
+			v.duplicates("duplicate-source", func(dot *Dotfile) string { return dot.Source }, func(first, second int) {
+				firstDot, secondDot := v.dotsConf.Dotfiles[first], v.dotsConf.Dotfiles[second]
+				err := fmt.Errorf("dotfiles `%s` and `%s` have the same source `%s`", firstDot.Name, secondDot.Name, secondDot.Source)
+				violations = append(violations, violation{err: err})
+			})
+			return violations, nil
+		},
+	},
+	{
+		ID:          "destination-overlap",
+		Severity:    SeverityError,
+		Description: "two dotfiles install to the same path or inside of one another",
+		Hint:        "change one of the destinations, or use `install_children` to install children side by side",
+		phase:       phaseDotfiles,
+		check: func(v *validator, _ int) ([]violation, error) {
+			overlaps, err := v.destinationOverlaps()
+			if err != nil || len(overlaps) == 0 {
+				return nil, err
+			}
+			return []violation{{err: &DestinationOverlapError{Overlaps: overlaps}}}, nil
+		},
+	},
+}
+
+// Rules every Rule in the order they're checked
+func Rules() []*Rule {
+	return append([]*Rule{}, rules...)
+}
+
+// LookupRule finds a Rule by its ID, nil if there isn't one
+func LookupRule(id string) *Rule {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Parses the license for rules that check it, nil if it's blank or invalid
+func (v *validator) license() *LicenseExpression {
+	if v.dotsConf.License == "" {
+		return nil
+	}
+	expression, err := ParseLicense(v.dotsConf.License)
+	if err != nil {
+		return nil
+	}
+	return expression
+}
+
+// Checks if the source of the dotfile at index is scanned for the rule ruleID, it isn't if the dotfile
+// ignores the rule or its source doesn't exist, which is reported by `source-missing`
+func (v *validator) scansSource(index int, ruleID string) bool {
+	if v.ignores.dotfile(ruleID, index) {
+		return false
+	}
+	_, err := os.Lstat(v.dotsConf.Dotfiles[index].Source)
+	return !os.IsNotExist(err)
+}
+
+// Calls report for every dotfile whose value, found by field, is the same as an earlier dotfile's
+// with the index of the earlier and later dotfile, unless either ignores the rule
+//
+// Blank values aren't duplicates, they're reported by rules such as `description-blank`.
+func (v *validator) duplicates(ruleID string, field func(dot *Dotfile) string, report func(first, second int)) {
+	seen := make(map[string]int)
+	for i := range v.dotsConf.Dotfiles {
+		value := field(&v.dotsConf.Dotfiles[i])
+		if value == "" {
+			continue
+		}
+		first, ok := seen[value]
+		if !ok {
+			seen[value] = i
+			continue
+		}
+		if !v.ignores.dotfile(ruleID, first) && !v.ignores.dotfile(ruleID, i) {
+			report(first, i)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	ids := make(map[string]bool)
+	for _, rule := range config.Rules() {
+		assert.False(t, ids[rule.ID], "rule `%s` is defined more than once", rule.ID)
+		ids[rule.ID] = true
+
+		assert.NotEmpty(t, rule.Description, "rule `%s` has no description", rule.ID)
+		assert.NotEmpty(t, rule.Hint, "rule `%s` has no hint", rule.ID)
+		assert.NotEqual(t, config.SeverityOff, rule.Severity, "rule `%s` is off by default", rule.ID)
+		assert.Equal(t, rule, config.LookupRule(rule.ID))
+	}
+	assert.Nil(t, config.LookupRule("not-a-rule"))
+}
+
+func TestParseSeverity(t *testing.T) {
+	for name, expected := range map[string]config.Severity{"off": config.SeverityOff, "Warning": config.SeverityWarning, "ERROR": config.SeverityError} {
+		severity, err := config.ParseSeverity(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, severity)
+	}
+
+	_, err := config.ParseSeverity("fatal")
+	assert.Error(t, err)
+}
+
+func TestValidateIgnore(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup rules_test.go testing: %w", err)
+
+	validationError := config.Validate(filepath.Join(testData, "ignore", ".dots.yml"))
+	assert.Equal(t, &config.ValidationError{
+		Warnings: []*config.Warning{
+			{
+				Message:        "`# dots:ignore not-a-rule` ignores a rule that doesn't exist",
+				Recommendation: "run `dots validate --list-rules` to list every rule",
+			},
+			{
+				Message:        "dotfile `bspwm` description shouldn't be left blank",
+				Recommendation: "describe the dotfile, `dots validate --fix` adds a placeholder",
+				Rule:           "description-blank",
+			},
+		},
+	}, validationError)
+}
+
+func TestValidateWithUserConfig(t *testing.T) {
+	testData, err := pathToTestData()
+	assert.NoErrorf(t, err, "failed to setup rules_test.go testing: %w", err)
+
+	dir, err := ioutil.TempDir("", "dots-rules")
+	assert.NoErrorf(t, err, "failed to setup rules_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	userConfigPath := filepath.Join(dir, "config.yml")
+	assert.NoError(t, ioutil.WriteFile(userConfigPath, []byte("rules:\n  description-blank: error\n"), 0644))
+	userConf, err := config.ReadUserConfig(userConfigPath)
+	assert.NoError(t, err)
+	assert.Equal(t, &config.UserConfig{Rules: map[string]config.Severity{"description-blank": config.SeverityError}}, userConf)
+
+	validationError := config.ValidateWith(filepath.Join(testData, "invalid-dot-blank-description.yml"), userConf)
+	assert.True(t, validationError.IsErr())
+	assert.Equal(t, "description-blank", validationError.Rule)
+	assert.EqualError(t, validationError.Err, "dotfile `bspwm` description shouldn't be left blank")
+
+	userConf.Rules["description-blank"] = config.SeverityOff
+	assert.Nil(t, config.ValidateWith(filepath.Join(testData, "invalid-dot-blank-description.yml"), userConf))
+}
+
+func TestReadUserConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-rules")
+	assert.NoErrorf(t, err, "failed to setup rules_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	userConf, err := config.ReadUserConfig(filepath.Join(dir, "missing.yml"))
+	assert.NoError(t, err)
+	assert.Equal(t, &config.UserConfig{}, userConf)
+
+	path := filepath.Join(dir, "config.yml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("cache:\n  offline: true\n  ttl: 24h\n  network_timeout: 90s\n  shallow: true\n"), 0644))
+	userConf, err = config.ReadUserConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, config.CacheSettings{Offline: true, TTL: config.Duration(24 * time.Hour), NetworkTimeout: config.Duration(90 * time.Second), Shallow: true}, userConf.Cache)
+
+	tests := map[string]string{
+		"unknown-rule.yml":      "rules:\n  not-a-rule: error\n",
+		"unknown-severity.yml":  "rules:\n  description-blank: fatal\n",
+		"unknown-field.yml":     "severities:\n  description-blank: error\n",
+		"invalid-duration.yml":  "cache:\n  ttl: 1 day\n",
+		"negative-duration.yml": "cache:\n  lock_timeout: -1m\n",
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+			_, err := config.ReadUserConfig(path)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// secretPattern a kind of secret and how to find it in a line
+type secretPattern struct {
+	kind    string
+	regexp  *regexp.Regexp
+	group   int     // Group of regexp that's the secret itself, checked against entropy
+	file    string  // Only lines of files with this name are checked, empty checks every file
+	entropy float64 // Minimum Shannon entropy in bits per character of the secret, 0 doesn't check entropy
+}
+
+// Every kind of secret that's scanned for
+var secretPatterns = []secretPattern{
+	{kind: "private key", regexp: regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+	{kind: "AWS access key ID", regexp: regexp.MustCompile(`\b(?:A3T[A-Z0-9]|AKIA|ASIA|AGPA|AIDA|AROA|AIPA|ANPA|ANVA)[A-Z0-9]{16}\b`)},
+	{kind: "AWS secret access key", regexp: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})\b`), group: 1},
+	{kind: "GitHub token", regexp: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{kind: "GitLab token", regexp: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{kind: "Slack token", regexp: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{kind: "Google API key", regexp: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{kind: "Stripe secret key", regexp: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+	{kind: "password in URL", regexp: regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:([^/\s:@$]{3,})@`), group: 1},
+	{kind: "`.netrc` password", regexp: regexp.MustCompile(`\bpassword\s+(\S+)`), group: 1, file: ".netrc"},
+	{kind: "`.netrc` password", regexp: regexp.MustCompile(`\bpassword\s+(\S+)`), group: 1, file: "_netrc"},
+	{
+		kind:    "high entropy secret",
+		regexp:  regexp.MustCompile(`(?i)(?:api_?key|apikey|secret|token|passw(?:or)?d|credentials?|auth)["']?\s*[=:]\s*["']?([A-Za-z0-9_\-/+=.]{16,})`),
+		group:   1,
+		entropy: 3.5,
+	},
+}
+
+const (
+	// Marker that allows a secret on the line it's on
+	allowSecretMarker = "dots:allow-secret"
+	// Files larger than this aren't scanned
+	maxSecretScanSize = 1 << 20
+)
+
+// SecretFinding a possible secret in a file
+type SecretFinding struct {
+	Path string // Path to the file containing the secret
+	Line int    // 1-based line the secret is on
+	Kind string // Kind of secret, such as `private key`
+}
+
+// SecretError is an error dictating that the sources of Dotfiles contain possible secrets
+type SecretError struct {
+	Dotfiles []DotfileSecrets // Every Dotfile with possible secrets in the order they appear
+}
+
+// DotfileSecrets the possible secrets in a Dotfile's source
+type DotfileSecrets struct {
+	Name     string          // Name of the Dotfile
+	Findings []SecretFinding // Every possible secret found
+}
+
+// Error returns a String stating every possible secret and where it is, one per line
+func (se *SecretError) Error() string {
+	var messages []string
+	for _, dot := range se.Dotfiles {
+		for _, finding := range dot.Findings {
+			messages = append(messages, fmt.Sprintf("dotfile `%s` `%s:%d` contains a possible %s", dot.Name, finding.Path, finding.Line, finding.Kind))
+		}
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ScanSecrets scans every file in source, which can be a single file, for possible secrets
+//
+// Lines containing `dots:allow-secret`, generally in a comment, and lines allowed by allowed are skipped.
+// Symbolic links to files are followed. Binary files and files larger than 1MiB aren't scanned, see
+// `UnscannedFiles`.
+func ScanSecrets(source string, allowed func(path string, line int) bool) ([]SecretFinding, error) {
+	scanned, _, err := secretFiles(source)
+	if err != nil {
+		return nil, err
+	}
+	var findings []SecretFinding
+	for _, path := range scanned {
+		fileFindings, err := scanFile(path, allowed)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, fileFindings...)
+	}
+	return findings, nil
+}
+
+// UnscannedFiles returns every file in source, which can be a single file, that's too large for
+// ScanSecrets to scan
+func UnscannedFiles(source string) ([]string, error) {
+	_, unscanned, err := secretFiles(source)
+	return unscanned, err
+}
+
+// Files in source that are scanned for secrets and those that are too large to be, symbolic links to
+// files are followed while links that are broken or to directories are skipped
+func secretFiles(source string) (scanned, unscanned []string, err error) {
+	err = filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read `%s`: %w", path, err)
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(path); err != nil {
+				return nil
+			}
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if info.Size() > maxSecretScanSize {
+			unscanned = append(unscanned, path)
+		} else {
+			scanned = append(scanned, path)
+		}
+		return nil
+	})
+	return scanned, unscanned, err
+}
+
+// Scans a single file for possible secrets, binary files are skipped
+func scanFile(path string, allowed func(path string, line int) bool) ([]SecretFinding, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file `%s`: %w", path, err)
+	}
+	header := contents
+	if len(header) > 8000 {
+		header = header[:8000]
+	}
+	if bytes.IndexByte(header, 0) != -1 {
+		return nil, nil
+	}
+
+	var findings []SecretFinding
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSecretScanSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.Contains(text, allowSecretMarker) || (allowed != nil && allowed(path, line)) {
+			continue
+		}
+		if kind := findSecret(filepath.Base(path), text); kind != "" {
+			findings = append(findings, SecretFinding{Path: path, Line: line, Kind: kind})
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file `%s`: %w", path, err)
+	}
+	return findings, nil
+}
+
+// The kind of the first secret found in line of the file named name, empty if there isn't one
+func findSecret(name, line string) string {
+	for _, pattern := range secretPatterns {
+		if pattern.file != "" && pattern.file != name {
+			continue
+		}
+		for _, match := range pattern.regexp.FindAllStringSubmatch(line, -1) {
+			secret := match[pattern.group]
+			if pattern.entropy != 0 && (strings.ContainsAny(secret, "$") || shannonEntropy(secret) < pattern.entropy) {
+				continue
+			}
+			return pattern.kind
+		}
+	}
+	return ""
+}
+
+// Shannon entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]float64)
+	for _, c := range s {
+		counts[c]++
+	}
+	length := float64(len([]rune(s)))
+	entropy := 0.0
+	for _, count := range counts {
+		p := count / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Checks if the possible secret at line of path is allowed by `allow_secrets`
+//
+// Each entry is a path relative to the project root, which can be a glob pattern, optionally
+// followed by `:line` to only allow that line.
+func (v *validator) secretAllowed(path string, line int) bool {
+	root, err := filepath.Abs(v.root)
+	if err != nil {
+		return false
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, entry := range v.dotsConf.AllowSecrets {
+		pattern, entryLine := splitAllowSecret(entry)
+		if entryLine != 0 && entryLine != line {
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched || isWithin(filepath.FromSlash(pattern), filepath.FromSlash(rel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Splits an `allow_secrets` entry into its path or pattern and line, 0 if it allows every line
+func splitAllowSecret(entry string) (string, int) {
+	if colon := strings.LastIndex(entry, ":"); colon != -1 {
+		if number, err := strconv.Atoi(entry[colon+1:]); err == nil {
+			return entry[:colon], number
+		}
+	}
+	return entry, 0
+}
+
+// Checks that no `allow_secrets` entry allows every file in the project root, which would stop
+// scanning for secrets entirely
+func checkAllowSecrets(entries []string) error {
+	for _, entry := range entries {
+		pattern, _ := splitAllowSecret(entry)
+		if cleaned := filepath.Clean(filepath.FromSlash(strings.TrimSpace(pattern))); cleaned == "." || cleaned == string(filepath.Separator) {
+			return fmt.Errorf("`allow_secrets` entry `%s` allows every file in the project root, list the files with false positives instead", entry)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Secrets are assembled at runtime so this file isn't itself flagged by secret scanners
+var (
+	fakePrivateKey = "-----BEGIN OPENSSH " + "PRIVATE KEY-----"
+	fakeAWSKeyID   = "AKIA" + "IOSFODNN7EXAMPLE"
+	fakeGitHub     = "ghp_" + strings.Repeat("a1B2c3D4e5", 4)
+	fakeAPIKey     = "k9Qz" + "T2vLx8Rw4NpYb7Hc"
+)
+
+// Writes files, by their path relative to dir, creating parent directories
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+}
+
+func TestScanSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-secrets")
+	assert.NoErrorf(t, err, "failed to setup secrets_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"ssh/id_ed25519": fakePrivateKey + "\n",
+		"zsh/.zshrc": strings.Join([]string{
+			"export EDITOR=nvim",
+			"export AWS_ACCESS_KEY_ID=" + fakeAWSKeyID,
+			"export GITHUB_TOKEN=" + fakeGitHub,
+			"export API_KEY=" + fakeAPIKey,
+			"export API_KEY=" + fakeAPIKey + " # dots:allow-secret",
+			"export GITHUB_TOKEN=$(pass show github)",
+			"export PASSWORD=aaaaaaaaaaaaaaaaaaaa",
+		}, "\n"),
+		"netrc/.netrc":    "machine example.com login nick password hunter2\n",
+		"netrc/notnetrc":  "password hunter2\n",
+		"binary/blob.bin": "\x00" + fakeAWSKeyID,
+	})
+
+	findings, err := config.ScanSecrets(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []config.SecretFinding{
+		{Path: filepath.Join(dir, "netrc", ".netrc"), Line: 1, Kind: "`.netrc` password"},
+		{Path: filepath.Join(dir, "ssh", "id_ed25519"), Line: 1, Kind: "private key"},
+		{Path: filepath.Join(dir, "zsh", ".zshrc"), Line: 2, Kind: "AWS access key ID"},
+		{Path: filepath.Join(dir, "zsh", ".zshrc"), Line: 3, Kind: "GitHub token"},
+		{Path: filepath.Join(dir, "zsh", ".zshrc"), Line: 4, Kind: "high entropy secret"},
+	}, findings)
+
+	findings, err = config.ScanSecrets(filepath.Join(dir, "zsh", ".zshrc"), func(path string, line int) bool { return line != 3 })
+	assert.NoError(t, err)
+	assert.Equal(t, []config.SecretFinding{{Path: filepath.Join(dir, "zsh", ".zshrc"), Line: 3, Kind: "GitHub token"}}, findings)
+}
+
+func TestScanSecretsLinksAndLargeFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-secrets")
+	assert.NoErrorf(t, err, "failed to setup secrets_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"private/token": "export GITHUB_TOKEN=" + fakeGitHub + "\n",
+		"zsh/large.zsh": strings.Repeat("# padding\n", 1<<17) + "export GITHUB_TOKEN=" + fakeGitHub + "\n",
+	})
+	source := filepath.Join(dir, "zsh")
+	assert.NoError(t, os.Symlink(filepath.Join(dir, "private", "token"), filepath.Join(source, "token")))
+	assert.NoError(t, os.Symlink(filepath.Join(dir, "doesnt-exist"), filepath.Join(source, "broken")))
+
+	findings, err := config.ScanSecrets(source, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []config.SecretFinding{{Path: filepath.Join(source, "token"), Line: 1, Kind: "GitHub token"}}, findings)
+
+	unscanned, err := config.UnscannedFiles(source)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(source, "large.zsh")}, unscanned)
+}
+
+func TestValidateSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-secrets")
+	assert.NoErrorf(t, err, "failed to setup secrets_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	dotsConfig := `version: 1
+name: NickHackman/dotfiles
+license: MIT
+dotfiles:
+  - name: zsh
+    description: Z shell
+  - name: ssh
+    description: SSH config
+`
+	writeFiles(t, dir, map[string]string{
+		".dots.yml":      dotsConfig,
+		"zsh/.zshrc":     "export EDITOR=nvim\nexport GITHUB_TOKEN=" + fakeGitHub + "\n",
+		"ssh/id_ed25519": fakePrivateKey + "\n",
+	})
+
+	// Every dotfile with a secret is reported
+	validationError := config.Validate(filepath.Join(dir, ".dots.yml"))
+	assert.True(t, validationError.IsErr())
+	assert.Equal(t, "secret", validationError.Rule)
+	assert.Equal(t, &config.SecretError{Dotfiles: []config.DotfileSecrets{
+		{Name: "zsh", Findings: []config.SecretFinding{{Path: filepath.Join(dir, "zsh", ".zshrc"), Line: 2, Kind: "GitHub token"}}},
+		{Name: "ssh", Findings: []config.SecretFinding{{Path: filepath.Join(dir, "ssh", "id_ed25519"), Line: 1, Kind: "private key"}}},
+	}}, validationError.Err)
+	assert.EqualError(t, validationError.Err, strings.Join([]string{
+		"dotfile `zsh` `" + filepath.Join(dir, "zsh", ".zshrc") + ":2` contains a possible GitHub token",
+		"dotfile `ssh` `" + filepath.Join(dir, "ssh", "id_ed25519") + ":1` contains a possible private key",
+	}, "\n"))
+
+	// Secrets in files that aren't committed are reported before they're committed
+	if _, err := exec.LookPath("git"); err == nil {
+		runGit(t, dir, "init", "--quiet")
+		runGit(t, dir, "add", ".dots.yml")
+		runGit(t, dir, "commit", "--quiet", "-m", "Add dots config")
+		validationError = config.Validate(filepath.Join(dir, ".dots.yml"))
+		assert.True(t, validationError.IsErr())
+		assert.Equal(t, "secret", validationError.Rule)
+		assert.NoError(t, os.RemoveAll(filepath.Join(dir, ".git")))
+	}
+
+	writeFiles(t, dir, map[string]string{".dots.yml": dotsConfig + "allow_secrets:\n  - zsh/.zshrc:2\n  - ssh\n"})
+	assert.Nil(t, config.Validate(filepath.Join(dir, ".dots.yml")))
+
+	// Allowed paths are relative to the project root regardless of the working directory
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	relPath, err := filepath.Rel(wd, filepath.Join(dir, ".dots.yml"))
+	assert.NoError(t, err)
+	assert.Nil(t, config.Validate(relPath))
+
+	writeFiles(t, dir, map[string]string{"zsh/large.zsh": strings.Repeat("# padding\n", 1<<17)})
+	assert.Equal(t, &config.ValidationError{
+		Warnings: []*config.Warning{{
+			Message:        "dotfile `zsh` `" + filepath.Join(dir, "zsh", "large.zsh") + "` is too large to scan for secrets",
+			Recommendation: "check the file for secrets yourself, files larger than 1MiB aren't scanned",
+			Rule:           "secret-unscanned",
+		}},
+	}, config.Validate(filepath.Join(dir, ".dots.yml")))
+	assert.NoError(t, os.Remove(filepath.Join(dir, "zsh", "large.zsh")))
+
+	writeFiles(t, dir, map[string]string{".dots.yml": dotsConfig + "allow_secrets:\n  - zsh/.zshrc:1\n  - ssh/*\n"})
+	validationError = config.Validate(filepath.Join(dir, ".dots.yml"))
+	assert.True(t, validationError.IsErr())
+	assert.Equal(t, "secret", validationError.Rule)
+}
+
+func TestParseAllowSecretsEveryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-secrets")
+	assert.NoErrorf(t, err, "failed to setup secrets_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+
+	for _, entry := range []string{`""`, `"."`, `"./"`, `":2"`} {
+		t.Run(entry, func(t *testing.T) {
+			writeFiles(t, dir, map[string]string{".dots.yml": "name: NickHackman/dotfiles\nlicense: MIT\nallow_secrets:\n  - " + entry + "\n"})
+			_, err := config.ParseFile(filepath.Join(dir, ".dots.yml"))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "allows every file in the project root")
+		})
+	}
+
+	writeFiles(t, dir, map[string]string{".dots.yml": "name: NickHackman/dotfiles\nlicense: MIT\nallow_secrets:\n  - zsh\n"})
+	_, err = config.ParseFile(filepath.Join(dir, ".dots.yml"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UserConfig the configuration of dots itself for the current user, as opposed to a dots config
+// which describes a repository of dotfiles
+type UserConfig struct {
+	Rules map[string]Severity `yaml:"rules,omitempty"` // Severity of Rules by their ID, overriding their default severity
+	Cache CacheSettings       `yaml:"cache,omitempty"` // How repositories in the cache are fetched
+}
+
+// CacheSettings how repositories in the cache are fetched, durations are written such as `24h` or `90s`
+type CacheSettings struct {
+	Offline        bool     `yaml:"offline,omitempty"`         // Never access the network, only use repositories already in the cache
+	TTL            Duration `yaml:"ttl,omitempty"`             // How long after being fetched a repository is fresh, 0 for forever
+	NetworkTimeout Duration `yaml:"network_timeout,omitempty"` // How long fetching a repository can take before failing, 0 for the default
+	LockTimeout    Duration `yaml:"lock_timeout,omitempty"`    // How long to wait for another dots process, 0 for the default
+	Shallow        bool     `yaml:"shallow,omitempty"`         // Only fetch the latest commits of repositories
+}
+
+// Duration a time.Duration written as a string, such as `24h`
+type Duration time.Duration
+
+// UnmarshalYAML decodes a Duration from a string, such as `24h`, it CANNOT be negative
+func (duration *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration `%s`, write it such as `24h` or `90s`", value.Line, value.Value)
+	}
+	if parsed < 0 {
+		return fmt.Errorf("line %d: duration `%s` CANNOT be negative", value.Line, value.Value)
+	}
+	*duration = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML encodes a Duration as a string, such as `24h0m0s`
+func (duration Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(duration).String(), nil
+}
+
+// UnmarshalYAML decodes a Severity from its name, such as `warning`
+func (severity *Severity) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := ParseSeverity(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*severity = parsed
+	return nil
+}
+
+// MarshalYAML encodes a Severity as its name, such as `warning`
+func (severity Severity) MarshalYAML() (interface{}, error) {
+	return severity.String(), nil
+}
+
+// UserConfigPath the path to the user config, `<config>/dots/config.yml`
+func UserConfigPath() (string, error) {
+	configDir, err := ResolvePlaceholder("config")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "dots", "config.yml"), nil
+}
+
+// LoadUserConfig reads the user config at `UserConfigPath`, if it doesn't exist an empty UserConfig is returned
+func LoadUserConfig() (*UserConfig, error) {
+	path, err := UserConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return ReadUserConfig(path)
+}
+
+// ReadUserConfig reads the user config at path, if it doesn't exist an empty UserConfig is returned
+//
+// Every Rule in `rules` MUST exist.
+func ReadUserConfig(path string) (*UserConfig, error) {
+	userConf := &UserConfig{}
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return userConf, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read user config `%s`: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(userConf); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse user config `%s`: %w", path, err)
+	}
+	for id := range userConf.Rules {
+		if LookupRule(id) == nil {
+			return nil, fmt.Errorf("user config `%s` sets the severity of `%s` which isn't a rule, run `dots validate --list-rules` to list every rule", path, id)
+		}
+	}
+	return userConf, nil
+}
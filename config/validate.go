@@ -1,17 +1,17 @@
 package config
 
 import (
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"reflect"
+	"path/filepath"
+	"strings"
 )
 
 // ValidationError a validation Error and or Warnings
 type ValidationError struct {
 	Warnings []*Warning // A slice of Warnings and their corresponding recommendations
 	Err      error      // Error that occurred
+	Rule     string     // ID of the Rule that reported Err, empty if Err isn't from a Rule such as failing to parse
 }
 
 // Error is shorthand for ValidationError.Err.Error(), empty string if no Error present
@@ -31,133 +31,194 @@ func (ve *ValidationError) IsErr() bool {
 type Warning struct {
 	Message        string // Warning message
 	Recommendation string // Recommendation on how to fix this warning
+	Rule           string // ID of the Rule that reported this warning, empty if it isn't from a Rule
 }
 
 type validator struct {
-	dotsConf *DotsConfig
-	validErr *ValidationError
+	dotsConf   *DotsConfig
+	validErr   *ValidationError
+	root       string               // Project root, the directory containing the dots config
+	severities map[string]Severity  // Severities set in the user config by Rule ID
+	ignores    *ignores             // Rules ignored by `# dots:ignore` comments
+	repo       *gitRepository       // Git repository containing root, nil if there isn't one
+	repoOpened bool                 // If repo has been opened
+	vcsName    string               // Name of the version control system containing root, such as `Mercurial`, empty if there isn't one
+	statuses   map[int][]SourceFile // Files that aren't committed as is by the index of the dotfile
 }
 
-// Validate validates a dots.yml file
+// Validate validates a dots.yml file using the default severity of every Rule
 func Validate(path string) *ValidationError {
+	return ValidateWith(path, nil)
+}
+
+// ValidateWith validates a dots.yml file using the severities set in userConf, userConf can be nil
+//
+// Every Rule is checked in order, see `Rules`, the first violation of a Rule with SeverityError stops
+// validation and is returned as Err along with the Warnings reported before it. Rules can also be
+// suppressed by `# dots:ignore rule-id` comments in the dots config.
+func ValidateWith(path string, userConf *UserConfig) *ValidationError {
 	dotsConf, err := ParseFile(path)
 	if err != nil {
 		return &ValidationError{Err: err}
 	}
-	validator := &validator{dotsConf: dotsConf, validErr: &ValidationError{}}
-	validator.validateName()
+	ignores, err := readIgnores(path)
+	if err != nil {
+		return &ValidationError{Err: err}
+	}
 
-	if validator.dotsConf.License == "" {
-		err := errors.New("license is required, if you're not sure which license consult https://choosealicense.com/")
-		return &ValidationError{Err: err, Warnings: validator.validErr.Warnings}
+	// Sources are absolute, so the root is as well for paths relative to it
+	root, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return &ValidationError{Err: err}
+	}
+	validator := &validator{dotsConf: dotsConf, validErr: &ValidationError{}, root: root, ignores: ignores}
+	if userConf != nil {
+		validator.severities = userConf.Rules
+	}
+	for _, id := range ignores.unknown {
+		message := fmt.Sprintf("`# dots:ignore %s` ignores a rule that doesn't exist", id)
+		recommendation := "run `dots validate --list-rules` to list every rule"
+		validator.validErr.Warnings = append(validator.validErr.Warnings, &Warning{Message: message, Recommendation: recommendation})
 	}
 
-	if err = validator.validateDots(); err != nil {
+	if err = validator.run(); err != nil {
 		return &ValidationError{Err: err, Warnings: validator.validErr.Warnings}
 	}
-
-	if len(validator.validErr.Warnings) != 0 {
+	if validator.validErr.IsErr() || len(validator.validErr.Warnings) != 0 {
 		return validator.validErr
 	}
-
 	return nil
 }
 
-// Validates .dots.ya?ml Name to not be blank
-func (v *validator) validateName() {
-	if v.dotsConf.Name != "" {
-		return
+// Severity of rule, either set in the user config or its default
+func (v *validator) severity(rule *Rule) Severity {
+	if severity, ok := v.severities[rule.ID]; ok {
+		return severity
 	}
-
-	Message := "dots config name shouldn't be left blank, isn't directly installable"
-	Recommendation := "set name to default value `YourName/dotfiles`"
-	warn := &Warning{Message, Recommendation}
-	v.validErr.Warnings = append(v.validErr.Warnings, warn)
+	return rule.Severity
 }
 
-// Validate dotfiles
-//
-// Check individual fields
-//
-// Name            - MUST exist and not be empty
-// Source          - MUST exist
-// Destination     - Probably shouldn't equal `~` or `/`
-// Description     - shouldn't be empty
-// InstallChildren - MUST have children
-func (v *validator) validateDots() error {
-	if v.dotsConf.Dotfiles == nil || len(v.dotsConf.Dotfiles) == 0 {
-		return nil
-	}
-
-	for i, dot := range v.dotsConf.Dotfiles {
-		if dot.Name == "" {
-			return fmt.Errorf("dotfile number `%d` name is blank, but field is required", i+1)
+// Checks every Rule by phase stopping at the first violation with SeverityError, errors returned are
+// failures to check a Rule rather than violations
+func (v *validator) run() error {
+	for _, phase := range []rulePhase{phaseConfig, phaseSources, phaseDotfile, phaseDotfiles} {
+		indexes := []int{0}
+		if phase == phaseDotfile {
+			indexes = make([]int, len(v.dotsConf.Dotfiles))
+			for i := range indexes {
+				indexes[i] = i
+			}
 		}
 
-		if _, err := os.Stat(dot.Source); os.IsNotExist(err) {
-			return fmt.Errorf("dotfile `%s` source field `%s` does not exist", dot.Name, dot.Source)
+		for _, index := range indexes {
+			for _, rule := range rules {
+				if rule.phase != phase {
+					continue
+				}
+				if stop, err := v.check(rule, index); stop || err != nil {
+					return err
+				}
+			}
 		}
+	}
+	return nil
+}
 
-		if dot.Description == "" {
-			Message := fmt.Sprintf("dotfile `%s` description shouldn't be left blank", dot.Name)
-			v.validErr.Warnings = append(v.validErr.Warnings, &Warning{Message: Message})
+// Checks a single Rule reporting its violations, returns true if a violation is an error
+func (v *validator) check(rule *Rule, index int) (bool, error) {
+	severity := v.severity(rule)
+	if severity == SeverityOff || v.ignores.file[rule.ID] || (rule.phase == phaseDotfile && v.ignores.dotfile(rule.ID, index)) {
+		return false, nil
+	}
+
+	violations, err := rule.check(v, index)
+	if err != nil {
+		return true, err
+	}
+	for _, violation := range violations {
+		if severity == SeverityError {
+			v.validErr.Err, v.validErr.Rule = violation.err, rule.ID
+			return true, nil
 		}
 
-		if dot.InstallChildren {
-			files, err := ioutil.ReadDir(dot.Source)
-			if err != nil {
-				return fmt.Errorf("dotfile `%s` failed to read directory `%s`: %w", dot.Name, dot.Source, err)
-			}
-			if len(files) == 0 {
-				return fmt.Errorf("dotfile `%s` has `install_children` set, but has 0 children in source `%s`", dot.Name, dot.Source)
-			}
+		recommendation := violation.recommendation
+		if recommendation == "" {
+			recommendation = rule.Hint
 		}
+		v.validErr.Warnings = append(v.validErr.Warnings, &Warning{Message: violation.err.Error(), Recommendation: recommendation, Rule: rule.ID})
 	}
-	fields := reflect.TypeOf(v.dotsConf.Dotfiles[0])
-	numFields := fields.NumField()
-	for i := 0; i < numFields; i++ {
-		if err := v.validateDuplicateDotVals(i); err != nil {
-			return err
+	return false, nil
+}
+
+// DestinationOverlap two dotfiles that install to the same path, or where one installs inside of the other
+type DestinationOverlap struct {
+	First  string // Name of the dotfile that appears first
+	Second string // Name of the dotfile that appears second
+	Outer  string // Path installed to that contains Path, equal to Path if both install to the same path
+	Path   string // Exact path where the dotfiles overlap
+}
+
+// DestinationOverlapError is an error dictating that dotfiles will overwrite one another when installed
+type DestinationOverlapError struct {
+	Overlaps []DestinationOverlap // Every pair of dotfiles that overlap
+}
+
+// Error returns a String stating every pair of dotfiles that overlap, one per line
+func (doe *DestinationOverlapError) Error() string {
+	messages := make([]string, len(doe.Overlaps))
+	for i, overlap := range doe.Overlaps {
+		if overlap.Outer == overlap.Path {
+			messages[i] = fmt.Sprintf("dotfiles `%s` and `%s` have the same destination `%s` and will overwrite one another", overlap.First, overlap.Second, overlap.Path)
+		} else {
+			messages[i] = fmt.Sprintf("dotfiles `%s` and `%s` overlap at `%s` which is inside of `%s`", overlap.First, overlap.Second, overlap.Path, overlap.Outer)
 		}
 	}
-	return nil
+	return strings.Join(messages, "\n")
 }
 
-// Validates duplicate fields in Dots by fieldName, hard errors on duplicate names, destinations, and sources
-func (v *validator) validateDuplicateDotVals(fieldIndex int) error {
-	var DupMap = make(map[string]struct {
-		name  string
-		index int
-	})
-
-	for i, dot := range v.dotsConf.Dotfiles {
-		fieldValue := reflect.ValueOf(dot).Field(fieldIndex).String()
-		fieldName := reflect.TypeOf(dot).Field(fieldIndex).Name
-		if prevDot, ok := DupMap[fieldValue]; ok {
-			var Message string
-			switch fieldName {
-			case "Name":
-				return fmt.Errorf("dotfiles with index `%d` and `%d` both have the same name `%s`", i+1, prevDot.index+1, dot.Name)
-			case "Destination":
-				return fmt.Errorf("dotfiles `%s` and `%s` have the same destination `%s` and will overwrite one another", prevDot.name, dot.Name, dot.Destination)
-			case "Source":
-				return fmt.Errorf("dotfiles `%s` and `%s` have the same source `%s`", prevDot.name, dot.Name, dot.Source)
-			case "Description":
-				Message = fmt.Sprintf("dotfiles %s and %s have the same description `%s`", prevDot.name, dot.Name, dot.Description)
-			case "InstallChildren":
+// Finds every pair of dotfiles that install to the same path or inside of one another,
+// unless either ignores `destination-overlap`
+//
+// Dotfiles with `install_children` are compared by each of their children,
+// so multiple dotfiles can install their children to `~`.
+func (v *validator) destinationOverlaps() ([]DestinationOverlap, error) {
+	paths := make([][]string, len(v.dotsConf.Dotfiles))
+	for i := range v.dotsConf.Dotfiles {
+		dot := &v.dotsConf.Dotfiles[i]
+		// The children of a source that doesn't exist aren't known, it's reported by `source-missing`
+		if _, err := os.Stat(dot.Source); dot.InstallChildren && os.IsNotExist(err) {
+			continue
+		}
+		dotPaths, err := dot.InstallPaths()
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = dotPaths
+	}
+
+	var overlaps []DestinationOverlap
+	for i, first := range v.dotsConf.Dotfiles {
+		for j := i + 1; j < len(v.dotsConf.Dotfiles); j++ {
+			second := v.dotsConf.Dotfiles[j]
+			if v.ignores.dotfile("destination-overlap", i) || v.ignores.dotfile("destination-overlap", j) {
 				continue
-			default:
-				panic(fmt.Sprintf("Unknown field `%s` in Dotfile if duplicates matters please implement a case for it in validateDuplicateDotVals; otherwise, exclude it.", fieldName))
 			}
-			warn := &Warning{Message: Message}
-			v.validErr.Warnings = append(v.validErr.Warnings, warn)
-			continue
+			for _, firstPath := range paths[i] {
+				for _, secondPath := range paths[j] {
+					overlap := DestinationOverlap{First: first.Name, Second: second.Name}
+					switch {
+					case isWithin(firstPath, secondPath):
+						overlap.Outer, overlap.Path = firstPath, secondPath
+					case isWithin(secondPath, firstPath):
+						overlap.Outer, overlap.Path = secondPath, firstPath
+					default:
+						continue
+					}
+					overlaps = append(overlaps, overlap)
+				}
+			}
 		}
-		DupMap[fieldValue] = struct {
-			name  string
-			index int
-		}{name: dot.Name, index: i}
 	}
 
-	return nil
+	return overlaps, nil
 }
@@ -64,6 +64,7 @@ func TestValidateInvalid(t *testing.T) {
 					{
 						Message:        "dots config name shouldn't be left blank, isn't directly installable",
 						Recommendation: "set name to default value `YourName/dotfiles`",
+						Rule:           "name-blank",
 					},
 				},
 			},
@@ -71,57 +72,166 @@ func TestValidateInvalid(t *testing.T) {
 		{
 			path: "invalid-dot-blank-name.yml",
 			validationError: &config.ValidationError{
-				Err: errors.New("dotfile number `1` name is blank, but field is required"),
+				Err:  errors.New("dotfile number `1` name is blank, but field is required"),
+				Rule: "dotfile-name-blank",
 			},
 		},
 		{
 			path: "invalid-blank-license.yml",
 			validationError: &config.ValidationError{
-				Err: errors.New("license is required, if you're not sure which license consult https://choosealicense.com/"),
+				Err:  errors.New("license is required, if you're not sure which license consult https://choosealicense.com/"),
+				Rule: "license-missing",
 			},
 		},
 		{
 			path: "invalid-duplicate-dot-names.yml",
 			validationError: &config.ValidationError{
-				Err: errors.New("dotfiles with index `3` and `1` both have the same name `bspwm`"),
+				Err:  errors.New("dotfiles with index `3` and `1` both have the same name `bspwm`"),
+				Rule: "duplicate-name",
 			},
 		},
 		{
 			path: "invalid-duplicate-dot-destinations.yml",
 			validationError: &config.ValidationError{
-				Err: fmt.Errorf("dotfiles `bspwm` and `keybinds` have the same destination `%s` and will overwrite one another", homeDir),
+				Err: &config.DestinationOverlapError{
+					Overlaps: []config.DestinationOverlap{{First: "bspwm", Second: "keybinds", Outer: homeDir, Path: homeDir}},
+				},
+				Rule: "destination-overlap",
+			},
+		},
+		{
+			path: "invalid-nested-dot-destinations.yml",
+			validationError: &config.ValidationError{
+				Err: &config.DestinationOverlapError{
+					Overlaps: []config.DestinationOverlap{
+						{
+							First:  "config",
+							Second: "bspwm",
+							Outer:  filepath.Join(homeDir, ".config"),
+							Path:   filepath.Join(homeDir, ".config", "bspwm"),
+						},
+						{
+							First:  "keybinds",
+							Second: "xbindkeys",
+							Outer:  filepath.Join(homeDir, ".xbindkeysrc"),
+							Path:   filepath.Join(homeDir, ".xbindkeysrc"),
+						},
+					},
+				},
+				Rule: "destination-overlap",
 			},
 		},
 		{
 			path: "invalid-duplicate-dot-sources.yml",
 			validationError: &config.ValidationError{
-				Err: fmt.Errorf("dotfiles `bspwm` and `keybinds` have the same source `%s`", filepath.Join(testData, "bspwm")),
+				Err:  fmt.Errorf("dotfiles `bspwm` and `keybinds` have the same source `%s`", filepath.Join(testData, "bspwm")),
+				Rule: "duplicate-source",
 			},
 		},
 		{
 			path: "invalid-duplicate-dot-descriptions.yml",
 			validationError: &config.ValidationError{
-				Warnings: []*config.Warning{{Message: "dotfiles bspwm and keybinds have the same description `description`"}},
-				Err:      nil,
+				Warnings: []*config.Warning{
+					{
+						Message:        "dotfiles bspwm and keybinds have the same description `description`",
+						Recommendation: "describe what's specific to each dotfile",
+						Rule:           "duplicate-description",
+					},
+				},
+				Err: nil,
 			},
 		},
 		{
 			path: "invalid-dot-non-existing-source.yml",
 			validationError: &config.ValidationError{
-				Err: fmt.Errorf("dotfile `test2` source field `%s` does not exist", filepath.Join(testData, "test2")),
+				Err:  fmt.Errorf("dotfile `test2` source field `%s` does not exist", filepath.Join(testData, "test2")),
+				Rule: "source-missing",
+			},
+		},
+		{
+			path: "invalid-dot-source-outside-root.yml",
+			validationError: &config.ValidationError{
+				Err: &config.SourceOutsideRootError{
+					Name:     "cmd",
+					Path:     filepath.Join(filepath.Dir(testData), "cmd"),
+					Resolved: filepath.Join(filepath.Dir(testData), "cmd"),
+					Root:     testData,
+				},
+			},
+		},
+		{
+			path: "invalid-dot-source-absolute.yml",
+			validationError: &config.ValidationError{
+				Err: &config.SourceOutsideRootError{Name: "passwd", Path: "/etc/passwd", Resolved: "/etc/passwd", Root: testData},
 			},
 		},
 		{
 			path: "invalid-dot-install-children-no-children.yml",
 			validationError: &config.ValidationError{
-				Err: fmt.Errorf("dotfile `bspwm` has `install_children` set, but has 0 children in source `%s`", filepath.Join(testData, "bspwm")),
+				Err:  fmt.Errorf("dotfile `bspwm` has `install_children` set, but has 0 children in source `%s`", filepath.Join(testData, "bspwm")),
+				Rule: "install-children-empty",
+			},
+		},
+		{
+			path: "invalid-license-alias.yml",
+			validationError: &config.ValidationError{
+				Warnings: []*config.Warning{
+					{
+						Message:        "license `GPLv3` isn't written using SPDX license identifiers",
+						Recommendation: "set license to `GPL-3.0-only`",
+						Rule:           "license-not-canonical",
+					},
+				},
+			},
+		},
+		{
+			path: "invalid-license-unknown.yml",
+			validationError: &config.ValidationError{
+				Err:  &config.LicenseError{Expression: "MIT OR Not-A-License", Reason: "unknown SPDX license identifier `Not-A-License`"},
+				Rule: "license-invalid",
+			},
+		},
+		{
+			path: "invalid-version-newer.yml",
+			validationError: &config.ValidationError{
+				Err: fmt.Errorf("failed to parse `%s`: %w", filepath.Join(testData, "invalid-version-newer.yml"), &config.VersionError{Version: 1000}),
+			},
+		},
+		{
+			path: "invalid-placeholder-unknown.yml",
+			validationError: &config.ValidationError{
+				Err: &config.PlaceholderError{
+					Name:        "bspwm",
+					Field:       "destination",
+					Value:       "<xdg_config>/bspwm",
+					Placeholder: "<xdg_config>",
+					Reason:      "which isn't one of `<root>`, `<bin>`, `<cache>`, `<config>`, `<data>`, `<home>`, `<state>`",
+				},
+			},
+		},
+		{
+			path: "invalid-placeholder-root-destination.yml",
+			validationError: &config.ValidationError{
+				Err: &config.PlaceholderError{
+					Name:        "bspwm",
+					Field:       "destination",
+					Value:       "<root>/bspwm",
+					Placeholder: "<root>",
+					Reason:      "which is only valid in source",
+				},
 			},
 		},
 		{
 			path: "invalid-dot-blank-description.yml",
 			validationError: &config.ValidationError{
-				Warnings: []*config.Warning{{Message: "dotfile `bspwm` description shouldn't be left blank"}},
-				Err:      nil,
+				Warnings: []*config.Warning{
+					{
+						Message:        "dotfile `bspwm` description shouldn't be left blank",
+						Recommendation: "describe the dotfile, `dots validate --fix` adds a placeholder",
+						Rule:           "description-blank",
+					},
+				},
+				Err: nil,
 			},
 		},
 	}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+// FileStatus how a file in a Dotfile's source differs from what's committed
+type FileStatus int
+
+const (
+	// FileUntracked the file isn't tracked by git
+	FileUntracked FileStatus = iota
+	// FileIgnored the file is ignored by git, generally by a `.gitignore`
+	FileIgnored
+	// FileUncommitted the file is staged, but hasn't been committed
+	FileUncommitted
+	// FileModified the file is committed, but has modifications that aren't
+	FileModified
+)
+
+// Describes every FileStatus as it's used in an error message
+var fileStatusMessages = []string{"is untracked", "is ignored", "is staged but not committed", "has uncommitted modifications"}
+
+// SourceFile a file in a Dotfile's source that isn't committed as is
+type SourceFile struct {
+	Path   string     // Path to the file, or to a directory if everything inside of it is ignored
+	Status FileStatus // How the file differs from what's committed
+}
+
+// SourceStatusError is an error dictating that files in a Dotfile's source aren't committed, so they'd be
+// missing or different for anyone that installs the dotfile from the repository
+type SourceStatusError struct {
+	Name  string       // Name of the Dotfile
+	Files []SourceFile // Every file that isn't committed as is
+}
+
+// Error returns a String stating every file and how it differs from what's committed, one per line
+func (sse *SourceStatusError) Error() string {
+	messages := make([]string, len(sse.Files))
+	for i, file := range sse.Files {
+		messages[i] = fmt.Sprintf("dotfile `%s` `%s` %s", sse.Name, file.Path, fileStatusMessages[file.Status])
+	}
+	return strings.Join(messages, "\n")
+}
+
+// gitRepository the git repository that a dots config is inside of
+type gitRepository struct {
+	dir string // Directory git is run in, the project root
+}
+
+// Opens the git repository containing dir, nil if dir isn't inside of one or git isn't installed
+func openGitRepository(dir string) *gitRepository {
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return nil
+	}
+	return &gitRepository{dir: dir}
+}
+
+// Runs git in the project root returning the NUL separated fields of its output
+func (repo *gitRepository) git(args ...string) ([]string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run `git %s`: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.FieldsFunc(string(output), func(r rune) bool { return r == 0 }), nil
+}
+
+// Every file in source that isn't committed as is, sorted by path
+//
+// Deleted files aren't included, a source that's missing entirely is reported by `source-missing`.
+func (repo *gitRepository) status(source string) ([]SourceFile, error) {
+	pathspec, err := filepath.Rel(repo.dir, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find `%s` relative to `%s`: %w", source, repo.dir, err)
+	}
+
+	var files []SourceFile
+	add := func(status FileStatus, paths []string) {
+		for _, path := range paths {
+			files = append(files, SourceFile{Path: filepath.Join(repo.dir, filepath.FromSlash(strings.TrimSuffix(path, "/"))), Status: status})
+		}
+	}
+
+	untracked, err := repo.git("ls-files", "-z", "--others", "--exclude-standard", "--", pathspec)
+	if err != nil {
+		return nil, err
+	}
+	add(FileUntracked, untracked)
+	ignored, err := repo.git("ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory", "--", pathspec)
+	if err != nil {
+		return nil, err
+	}
+	add(FileIgnored, ignored)
+
+	if _, err = repo.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		// Nothing has been committed yet, so every tracked file is uncommitted
+		tracked, err := repo.git("ls-files", "-z", "--", pathspec)
+		if err != nil {
+			return nil, err
+		}
+		add(FileUncommitted, tracked)
+	} else {
+		changed, err := repo.git("diff", "HEAD", "-z", "--name-status", "--no-renames", "--relative", "--", pathspec)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(changed); i += 2 {
+			switch changed[i] {
+			case "A":
+				add(FileUncommitted, changed[i+1:i+2])
+			case "D":
+				// Deleted files are still committed, so they aren't missing for anyone installing
+			default:
+				add(FileModified, changed[i+1:i+2])
+			}
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// URL of every remote of the repository, normalized the same way as `GitRemote`
+func (repo *gitRepository) remotes() ([]string, error) {
+	output, err := exec.Command("git", "-C", repo.dir, "remote", "-v").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list git remotes of `%s`: %w", repo.dir, err)
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || seen[fields[1]] {
+			continue
+		}
+		seen[fields[1]] = true
+		urls = append(urls, normalizeRemote(fields[1]))
+	}
+	return urls, nil
+}
+
+// The version control system of the closest repository containing dir, nil if it isn't inside of one
+func detectVCS(dir string) *vcs.Cmd {
+	for current := filepath.Clean(dir); ; current = filepath.Dir(current) {
+		// FromDir only checks current when the source root is its parent
+		if cmd, _, err := vcs.FromDir(current, filepath.Dir(current)); err == nil {
+			return cmd
+		}
+		if filepath.Dir(current) == current {
+			return nil
+		}
+	}
+}
+
+// The git repository containing the project root, nil if there isn't one or it's another VCS
+func (v *validator) repository() *gitRepository {
+	if !v.repoOpened {
+		v.repoOpened = true
+		if cmd := detectVCS(v.root); cmd != nil {
+			v.vcsName = cmd.Name
+		}
+		if v.vcsName == "" || v.vcsName == "Git" {
+			v.repo = openGitRepository(v.root)
+		}
+	}
+	return v.repo
+}
+
+// Files in the source of the dotfile at index that aren't committed as is, nil if the project
+// isn't inside of a git repository or the source doesn't exist
+func (v *validator) sourceStatus(index int) ([]SourceFile, error) {
+	if files, ok := v.statuses[index]; ok {
+		return files, nil
+	}
+	repo := v.repository()
+	dot := v.dotsConf.Dotfiles[index]
+	if repo == nil {
+		return nil, nil
+	}
+	if _, err := os.Lstat(dot.Source); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	files, err := repo.status(dot.Source)
+	if err != nil {
+		return nil, fmt.Errorf("dotfile `%s` failed to check git status of source `%s`: %w", dot.Name, dot.Source, err)
+	}
+	if v.statuses == nil {
+		v.statuses = make(map[int][]SourceFile)
+	}
+	v.statuses[index] = files
+	return files, nil
+}
+
+// Violations for every file in the source of the dotfile at index with one of statuses
+func (v *validator) sourceViolations(index int, statuses ...FileStatus) ([]violation, error) {
+	files, err := v.sourceStatus(index)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []SourceFile
+	for _, file := range files {
+		for _, status := range statuses {
+			if file.Status == status {
+				matching = append(matching, file)
+			}
+		}
+	}
+	if len(matching) == 0 {
+		return nil, nil
+	}
+	return []violation{{err: &SourceStatusError{Name: v.dotsConf.Dotfiles[index].Name, Files: matching}}}, nil
+}
@@ -0,0 +1,130 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/NickHackman/dots/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Runs git in dir with an identity set, so commits work regardless of the global git config
+func runGit(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-c", "user.name=dots", "-c", "user.email=dots@example.com", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	assert.NoErrorf(t, err, "failed to run `git %v`: %s", args, output)
+}
+
+func TestValidateVCS(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "dots-vcs")
+	assert.NoErrorf(t, err, "failed to setup vcs_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.NoError(t, err)
+
+	writeFiles(t, dir, map[string]string{
+		".dots.yml": `version: 1
+name: NickHackman/dotfiles
+license: MIT
+URL: https://github.com/NickHackman/dotfiles
+dotfiles:
+  - name: zsh
+    description: Z shell
+  - name: bspwm
+    description: Binary Space Partition Window Manager
+`,
+		".gitignore":    ".zsh_history\n",
+		"zsh/.zshrc":    "export EDITOR=nvim\n",
+		"bspwm/bspwmrc": "bspc monitor -d I II III\n",
+	})
+	configPath := filepath.Join(dir, ".dots.yml")
+
+	runGit(t, dir, "init", "--quiet")
+	runGit(t, dir, "add", ".")
+	validationError := config.Validate(configPath)
+	assert.True(t, validationError.IsErr())
+	assert.Equal(t, "source-uncommitted", validationError.Rule)
+	assert.Equal(t, &config.SourceStatusError{
+		Name:  "zsh",
+		Files: []config.SourceFile{{Path: filepath.Join(dir, "zsh", ".zshrc"), Status: config.FileUncommitted}},
+	}, validationError.Err)
+
+	runGit(t, dir, "commit", "--quiet", "-m", "Add dotfiles")
+	assert.Nil(t, config.Validate(configPath))
+
+	// Sources are compared with the project root relative to the working directory
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	relPath, err := filepath.Rel(wd, configPath)
+	assert.NoError(t, err)
+	assert.Nil(t, config.Validate(relPath))
+
+	runGit(t, dir, "remote", "add", "origin", "git@github.com:NickHackman/dotfiles.git")
+	assert.Nil(t, config.Validate(configPath))
+
+	runGit(t, dir, "remote", "set-url", "origin", "https://github.com/NickHackman/dots.git")
+	writeFiles(t, dir, map[string]string{"bspwm/bspwmrc": "bspc monitor -d I II III IV\n"})
+	assert.NoError(t, os.Remove(filepath.Join(dir, "zsh", ".zshrc")))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "zsh", "plugins"), 0755))
+	assert.Equal(t, &config.ValidationError{
+		Warnings: []*config.Warning{
+			{
+				Message:        "URL `https://github.com/NickHackman/dotfiles` doesn't match any git remote, remotes are `https://github.com/NickHackman/dots`",
+				Recommendation: "set URL to `https://github.com/NickHackman/dots`",
+				Rule:           "url-remote-mismatch",
+			},
+			{
+				Message:        "dotfile `bspwm` `" + filepath.Join(dir, "bspwm", "bspwmrc") + "` has uncommitted modifications",
+				Recommendation: "commit or discard the modifications, anyone that installs the dotfile gets what's committed",
+				Rule:           "source-modified",
+			},
+		},
+	}, config.Validate(configPath))
+
+	writeFiles(t, dir, map[string]string{"zsh/.zsh_history": "ls\n", "zsh/aliases.zsh": "alias ll='ls -l'\n"})
+	validationError = config.Validate(configPath)
+	assert.True(t, validationError.IsErr())
+	assert.Equal(t, "source-uncommitted", validationError.Rule)
+	assert.Equal(t, &config.SourceStatusError{
+		Name: "zsh",
+		Files: []config.SourceFile{
+			{Path: filepath.Join(dir, "zsh", ".zsh_history"), Status: config.FileIgnored},
+			{Path: filepath.Join(dir, "zsh", "aliases.zsh"), Status: config.FileUntracked},
+		},
+	}, validationError.Err)
+	assert.EqualError(t, validationError.Err, "dotfile `zsh` `"+filepath.Join(dir, "zsh", ".zsh_history")+"` is ignored\n"+
+		"dotfile `zsh` `"+filepath.Join(dir, "zsh", "aliases.zsh")+"` is untracked")
+}
+
+func TestValidateOtherVCS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dots-vcs")
+	assert.NoErrorf(t, err, "failed to setup vcs_test.go testing can't create temporary directory: %w", err)
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.NoError(t, err)
+
+	// A Mercurial repository with the dots config in a subdirectory
+	root := filepath.Join(dir, "dotfiles")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".hg"), 0755))
+	writeFiles(t, root, map[string]string{
+		".dots.yml":  "version: 1\nname: NickHackman/dotfiles\nlicense: MIT\ndotfiles:\n  - name: zsh\n    description: Z shell\n",
+		"zsh/.zshrc": "export EDITOR=nvim\n",
+	})
+
+	assert.Equal(t, &config.ValidationError{
+		Warnings: []*config.Warning{{
+			Message:        "project root `" + root + "` is in a Mercurial repository, `url-remote-mismatch`, `source-uncommitted` and `source-modified` only check git repositories",
+			Recommendation: "check yourself that every source is committed and the URL matches the repository",
+			Rule:           "vcs-unsupported",
+		}},
+	}, config.Validate(filepath.Join(root, ".dots.yml")))
+}
@@ -3,7 +3,9 @@ module github.com/NickHackman/dots
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/logrusorgru/aurora v2.0.3+incompatible
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/cobra v1.0.0
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/tools v0.0.0-20200711155855-7342f9734a7d